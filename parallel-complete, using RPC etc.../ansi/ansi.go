@@ -0,0 +1,417 @@
+package ansi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+	"uk.ac.bris.cs/gameoflife/control"
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// This package draws the board in the terminal with ANSI escape codes, as an alternative to SDL over SSH
+// The view can be scrolled with the arrow keys and zoomed with + and -, f fits the whole board in the terminal
+// m switches between half blocks and braille, and the game's own keys and counts are passed on to it
+
+// Mode is how cells are drawn with characters
+type Mode int
+
+const (
+	// HalfBlock draws two cells, one above the other, in each character
+	HalfBlock Mode = iota
+	// Braille draws a 2x4 block of cells in each character, for a more detailed view
+	Braille
+)
+
+// Range of zoom, each pixel covers 2^zoom cells across so negative zooms show each cell bigger
+const (
+	minZoom = -3
+	maxZoom = 12
+)
+
+// frameInterval is the shortest time between two frames, so a fast game doesn't flood the terminal
+const frameInterval = time.Second / 30
+
+// ParseMode converts the name of a mode into a Mode
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "halfblock", "half-block", "blocks":
+		return HalfBlock, nil
+	case "braille":
+		return Braille, nil
+	}
+	return HalfBlock, errors.New("unknown terminal mode " + s + ", must be halfblock or braille")
+}
+
+// String gives the name of a mode
+func (m Mode) String() string {
+	if m == Braille {
+		return "braille"
+	}
+	return "halfblock"
+}
+
+// Size of the pixels each character covers
+func (m Mode) charSize() (w, h int) {
+	if m == Braille {
+		return 2, 4
+	}
+	return 1, 2
+}
+
+// Renderer keeps a copy of the board from the game's events, and draws part of it in the terminal
+type Renderer struct {
+	out  *bufio.Writer
+	size func() (cols, rows int)
+	mode Mode
+
+	board  [][]bool
+	width  int
+	height int
+
+	// The top left cell of the view, and how far out we are zoomed
+	viewX int
+	viewY int
+	zoom  int
+	// Whether the zoom has been set to fit the board yet, as we need the terminal size for it
+	fitted bool
+
+	// Shown in the status line at the bottom
+	turn    int
+	alive   int
+	state   string
+	message string
+
+	// Keys pressed in the terminal, and the ones which are for the game
+	terminalKeys <-chan rune
+	gameKeys     chan rune
+
+	// Frames are only drawn when something has changed, including the size of the terminal
+	dirty    bool
+	lastDraw time.Time
+	lastCols int
+	lastRows int
+	drawErr  error
+}
+
+// NewRenderer creates a renderer for a width x height board which draws to out
+// The terminal size is taken from out if it is a terminal, otherwise it is 80x24
+// keys are the keys pressed in the terminal, e.g. from control.ReadTerminalKeys
+func NewRenderer(out *os.File, width, height int, mode Mode, keys <-chan rune) *Renderer {
+	board := make([][]bool, height)
+	for y := range board {
+		board[y] = make([]bool, width)
+	}
+	fd := int(out.Fd())
+	return &Renderer{
+		out: bufio.NewWriterSize(out, 64*1024),
+		size: func() (int, int) {
+			cols, rows, err := term.GetSize(fd)
+			if err != nil || cols <= 0 || rows <= 1 {
+				return 80, 24
+			}
+			return cols, rows
+		},
+		mode:   mode,
+		board:  board,
+		width:  width,
+		height: height,
+		state:  "Executing",
+
+		terminalKeys: keys,
+		gameKeys:     make(chan rune, 10),
+
+		dirty: true,
+	}
+}
+
+// Start switches to the terminal's alternate screen so the shell is left as it was afterwards
+func (r *Renderer) Start() {
+	r.out.WriteString("\x1b[?1049h\x1b[?25l\x1b[2J")
+	r.out.Flush()
+}
+
+// Close shows the cursor again and returns to the normal screen
+// It returns the error from drawing to the terminal, if there was one
+func (r *Renderer) Close() error {
+	r.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	r.out.Flush()
+	return r.drawErr
+}
+
+// Keys gives the game's keys pressed in the terminal
+func (r *Renderer) Keys() <-chan rune {
+	return r.gameKeys
+}
+
+// Poll handles the keys pressed since it was last called, and draws a frame if one is due
+// Drawing stops if the terminal has gone, but events are still handled so the game isn't left waiting
+func (r *Renderer) Poll() {
+keyLoop:
+	for {
+		select {
+		case key := <-r.terminalKeys:
+			if gameKey, ok := r.HandleKey(key); ok {
+				select {
+				case r.gameKeys <- gameKey:
+				default:
+				}
+			}
+		default:
+			break keyLoop
+		}
+	}
+
+	if r.drawErr == nil && time.Since(r.lastDraw) >= frameInterval {
+		r.lastDraw = time.Now()
+		r.drawErr = r.Draw()
+	}
+}
+
+// HandleEvent updates the board and status line from an event
+// Nothing is drawn until Draw is called
+func (r *Renderer) HandleEvent(event gol.Event) {
+	switch e := event.(type) {
+	case gol.CellFlipped:
+		if e.Cell.X >= 0 && e.Cell.X < r.width && e.Cell.Y >= 0 && e.Cell.Y < r.height {
+			r.board[e.Cell.Y][e.Cell.X] = !r.board[e.Cell.Y][e.Cell.X]
+		}
+		return
+	case gol.TurnComplete:
+		r.turn = e.CompletedTurns
+	case gol.AliveCellsCount:
+		r.turn = e.CompletedTurns
+		r.alive = e.CellsCount
+	case gol.StateChange:
+		r.state = e.NewState.String()
+	case gol.FinalTurnComplete:
+		for y := range r.board {
+			for x := range r.board[y] {
+				r.board[y][x] = false
+			}
+		}
+		for _, c := range e.Alive {
+			if c.X >= 0 && c.X < r.width && c.Y >= 0 && c.Y < r.height {
+				r.board[c.Y][c.X] = true
+			}
+		}
+		r.turn = e.CompletedTurns
+		r.alive = len(e.Alive)
+		r.message = "Finished"
+	case gol.ActiveTilesCount:
+		return
+	default:
+		r.message = event.String()
+	}
+	r.dirty = true
+}
+
+// HandleKey moves the view for the renderer's own keys
+// The game's keys are returned with ok set, so they can be passed on to it
+func (r *Renderer) HandleKey(key rune) (gameKey rune, ok bool) {
+	viewW, viewH := r.viewCells()
+	switch key {
+	case control.KeyUp:
+		r.viewY -= maxInt(viewH/4, 1)
+	case control.KeyDown:
+		r.viewY += maxInt(viewH/4, 1)
+	case control.KeyLeft:
+		r.viewX -= maxInt(viewW/4, 1)
+	case control.KeyRight:
+		r.viewX += maxInt(viewW/4, 1)
+	case '+', '=':
+		r.setZoom(r.zoom - 1)
+	case '-', '_':
+		r.setZoom(r.zoom + 1)
+	case 'f':
+		r.fit()
+	case 'm':
+		if r.mode == HalfBlock {
+			r.mode = Braille
+		} else {
+			r.mode = HalfBlock
+		}
+		r.fit()
+	default:
+		if control.IsCountKey(key) {
+			return key, true
+		}
+		return control.ParseCommand(string(key))
+	}
+	r.clampView()
+	r.dirty = true
+	return 0, false
+}
+
+// Draw writes a frame to the terminal if anything has changed since the last one
+func (r *Renderer) Draw() error {
+	cols, rows := r.size()
+	if !r.dirty && cols == r.lastCols && rows == r.lastRows {
+		return nil
+	}
+	r.dirty = false
+	if cols != r.lastCols || rows != r.lastRows {
+		// Anything left over from the old size would stay on the screen
+		r.lastCols, r.lastRows = cols, rows
+		r.out.WriteString("\x1b[2J")
+	}
+	if !r.fitted {
+		r.fit()
+	}
+	r.clampView()
+
+	charW, charH := r.mode.charSize()
+	r.out.WriteString("\x1b[H")
+	for row := 0; row < rows-1; row++ {
+		for col := 0; col < cols; col++ {
+			px, py := col*charW, row*charH
+			if r.mode == Braille {
+				r.out.WriteRune(r.braille(px, py))
+			} else {
+				r.out.WriteString(r.halfBlock(px, py))
+			}
+		}
+		r.out.WriteString("\x1b[K\r\n")
+	}
+	r.out.WriteString("\x1b[7m" + fitLine(r.status(), cols) + "\x1b[0m\x1b[K")
+	return r.out.Flush()
+}
+
+// Get the half block character for the pixels at (px, py) and (px, py + 1)
+func (r *Renderer) halfBlock(px, py int) string {
+	top, bottom := r.pixel(px, py), r.pixel(px, py+1)
+	switch {
+	case top && bottom:
+		return "█"
+	case top:
+		return "▀"
+	case bottom:
+		return "▄"
+	}
+	return " "
+}
+
+// Bits of a braille character for each pixel, by column then row
+var brailleDots = [2][4]rune{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+
+// Get the braille character for the 2x4 pixels with (px, py) at the top left
+func (r *Renderer) braille(px, py int) rune {
+	c := rune(0x2800)
+	for dx := 0; dx < 2; dx++ {
+		for dy := 0; dy < 4; dy++ {
+			if r.pixel(px+dx, py+dy) {
+				c |= brailleDots[dx][dy]
+			}
+		}
+	}
+	if c == 0x2800 {
+		// A blank braille character can be drawn as dots by some fonts
+		return ' '
+	}
+	return c
+}
+
+// Work out if a pixel of the view is alive
+// Zoomed out, a pixel is alive if any of the cells it covers are
+func (r *Renderer) pixel(px, py int) bool {
+	if r.zoom < 0 {
+		x, y := r.viewX+px>>uint(-r.zoom), r.viewY+py>>uint(-r.zoom)
+		return x < r.width && y < r.height && r.board[y][x]
+	}
+
+	scale := 1 << uint(r.zoom)
+	startX, startY := r.viewX+px*scale, r.viewY+py*scale
+	endX, endY := minInt(startX+scale, r.width), minInt(startY+scale, r.height)
+	for y := startY; y < endY; y++ {
+		row := r.board[y]
+		for x := startX; x < endX; x++ {
+			if row[x] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Get the number of cells across and down the view
+func (r *Renderer) viewCells() (w, h int) {
+	cols, rows := r.size()
+	charW, charH := r.mode.charSize()
+	w, h = cols*charW, (rows-1)*charH
+	if r.zoom < 0 {
+		return maxInt(w>>uint(-r.zoom), 1), maxInt(h>>uint(-r.zoom), 1)
+	}
+	return w << uint(r.zoom), h << uint(r.zoom)
+}
+
+// Change the zoom, keeping the centre of the view in the same place
+func (r *Renderer) setZoom(zoom int) {
+	if zoom < minZoom || zoom > maxZoom {
+		return
+	}
+	oldW, oldH := r.viewCells()
+	r.zoom = zoom
+	newW, newH := r.viewCells()
+	r.viewX += (oldW - newW) / 2
+	r.viewY += (oldH - newH) / 2
+}
+
+// Zoom in as far as possible with the whole board still in view
+func (r *Renderer) fit() {
+	r.fitted = true
+	r.viewX, r.viewY = 0, 0
+	for r.zoom = minZoom; r.zoom < maxZoom; r.zoom++ {
+		w, h := r.viewCells()
+		if w >= r.width && h >= r.height {
+			return
+		}
+	}
+}
+
+// Keep the view on the board
+func (r *Renderer) clampView() {
+	w, h := r.viewCells()
+	r.viewX = maxInt(minInt(r.viewX, r.width-w), 0)
+	r.viewY = maxInt(minInt(r.viewY, r.height-h), 0)
+}
+
+// The line shown below the board
+func (r *Renderer) status() string {
+	scale := fmt.Sprintf("1:%d", 1<<uint(maxInt(r.zoom, 0)))
+	if r.zoom < 0 {
+		scale = fmt.Sprintf("%d:1", 1<<uint(-r.zoom))
+	}
+	status := fmt.Sprintf(" Turn %d  Alive %d  %s  Zoom %s  View %d,%d  [arrows] scroll [+/-] zoom [f]it [m]ode [p]ause [n]ext [s]ave [q]uit [k]ill",
+		r.turn, r.alive, r.state, scale, r.viewX, r.viewY)
+	if r.message != "" {
+		status = " " + r.message + " |" + status
+	}
+	return status
+}
+
+// Cut or pad a line to exactly the width of the terminal
+func fitLine(line string, cols int) string {
+	runes := []rune(line)
+	if len(runes) > cols {
+		return string(runes[:cols])
+	}
+	return line + strings.Repeat(" ", cols-len(runes))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
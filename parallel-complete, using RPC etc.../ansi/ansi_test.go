@@ -0,0 +1,254 @@
+package ansi
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/control"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// newTestRenderer creates a renderer for a width x height board which draws to a buffer
+// The terminal is cols x rows, which can be changed through the pointers returned
+func newTestRenderer(width, height, cols, rows int, mode Mode, alive ...util.Cell) (*Renderer, *bytes.Buffer, *int, *int) {
+	out := new(bytes.Buffer)
+	board := make([][]bool, height)
+	for y := range board {
+		board[y] = make([]bool, width)
+	}
+	for _, c := range alive {
+		board[c.Y][c.X] = true
+	}
+	r := &Renderer{
+		out:  bufio.NewWriter(out),
+		size: func() (int, int) { return cols, rows },
+		mode: mode,
+
+		board:  board,
+		width:  width,
+		height: height,
+		state:  "Executing",
+
+		gameKeys: make(chan rune, 10),
+		dirty:    true,
+	}
+	return r, out, &cols, &rows
+}
+
+func TestPixel(t *testing.T) {
+	r, _, _, _ := newTestRenderer(8, 8, 20, 5, HalfBlock, util.Cell{X: 3, Y: 2})
+	tests := []struct {
+		zoom, viewX, viewY int
+		px, py             int
+		expected           bool
+	}{
+		{0, 0, 0, 3, 2, true},
+		{0, 0, 0, 2, 2, false},
+		{0, 0, 0, 3, 3, false},
+		{0, 2, 1, 1, 1, true},
+		{-1, 0, 0, 6, 4, true},
+		{-1, 0, 0, 7, 5, true},
+		{-1, 0, 0, 5, 4, false},
+		{-1, 0, 0, 6, 6, false},
+		{-1, 0, 0, 100, 0, false},
+		{1, 0, 0, 1, 1, true},
+		{1, 0, 0, 0, 1, false},
+		{1, 0, 0, 1, 0, false},
+		{2, 0, 0, 0, 0, true},
+		{2, 0, 0, 1, 0, false},
+		{3, 0, 0, 0, 0, true},
+		{3, 0, 0, 1, 1, false},
+	}
+	for _, test := range tests {
+		r.zoom, r.viewX, r.viewY = test.zoom, test.viewX, test.viewY
+		if got := r.pixel(test.px, test.py); got != test.expected {
+			t.Errorf("Zoom %d view %d,%d: expected pixel %d,%d to be %v", test.zoom, test.viewX, test.viewY, test.px, test.py, test.expected)
+		}
+	}
+
+	// Zoomed out pixels can cover cells past the edge of the board
+	r, _, _, _ = newTestRenderer(5, 5, 20, 5, HalfBlock, util.Cell{X: 4, Y: 4})
+	r.zoom = 1
+	if !r.pixel(2, 2) {
+		t.Error("Expected the pixel over the edge of the board to be alive")
+	}
+	if r.pixel(3, 3) {
+		t.Error("Expected the pixel off the board to be dead")
+	}
+}
+
+func TestBraille(t *testing.T) {
+	r, _, _, _ := newTestRenderer(4, 4, 20, 5, Braille, util.Cell{X: 0, Y: 0}, util.Cell{X: 1, Y: 3}, util.Cell{X: 2, Y: 1})
+	if c := r.braille(0, 0); c != 0x2881 {
+		t.Errorf("Expected %q, got %q", rune(0x2881), c)
+	}
+	if c := r.braille(2, 0); c != 0x2802 {
+		t.Errorf("Expected %q, got %q", rune(0x2802), c)
+	}
+	if c := r.braille(4, 0); c != ' ' {
+		t.Errorf("Expected a blank character to be a space, got %q", c)
+	}
+
+	full, _, _, _ := newTestRenderer(2, 4, 20, 5, Braille)
+	for y := range full.board {
+		for x := range full.board[y] {
+			full.board[y][x] = true
+		}
+	}
+	if c := full.braille(0, 0); c != 0x28FF {
+		t.Errorf("Expected %q, got %q", rune(0x28FF), c)
+	}
+}
+
+func TestSetZoom(t *testing.T) {
+	// A 20x5 terminal shows 20x8 cells in half blocks
+	r, _, _, _ := newTestRenderer(100, 100, 20, 5, HalfBlock)
+	r.viewX, r.viewY = 50, 50
+
+	r.setZoom(1)
+	if r.zoom != 1 || r.viewX != 40 || r.viewY != 46 {
+		t.Errorf("Expected zoom 1 at 40,46, got zoom %d at %d,%d", r.zoom, r.viewX, r.viewY)
+	}
+	r.setZoom(0)
+	if r.zoom != 0 || r.viewX != 50 || r.viewY != 50 {
+		t.Errorf("Expected zoom 0 at 50,50, got zoom %d at %d,%d", r.zoom, r.viewX, r.viewY)
+	}
+	r.setZoom(-1)
+	if r.zoom != -1 || r.viewX != 55 || r.viewY != 52 {
+		t.Errorf("Expected zoom -1 at 55,52, got zoom %d at %d,%d", r.zoom, r.viewX, r.viewY)
+	}
+
+	for _, zoom := range []int{minZoom - 1, maxZoom + 1} {
+		r.setZoom(zoom)
+		if r.zoom != -1 || r.viewX != 55 || r.viewY != 52 {
+			t.Errorf("Expected zoom %d to be ignored, got zoom %d at %d,%d", zoom, r.zoom, r.viewX, r.viewY)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		width, height int
+		cols, rows    int
+		mode          Mode
+		expected      int
+	}{
+		{10, 4, 20, 5, HalfBlock, -1},
+		{10, 6, 20, 5, HalfBlock, 0},
+		{20, 8, 20, 5, HalfBlock, 0},
+		{21, 8, 20, 5, HalfBlock, 1},
+		{2, 1, 20, 5, HalfBlock, minZoom},
+		{100, 100, 20, 5, HalfBlock, 4},
+		{100, 100, 20, 5, Braille, 3},
+		{1, 8 << 13, 20, 5, HalfBlock, maxZoom},
+	}
+	for _, test := range tests {
+		r, _, _, _ := newTestRenderer(test.width, test.height, test.cols, test.rows, test.mode)
+		r.zoom, r.viewX, r.viewY = 2, 5, 5
+		r.fit()
+		if r.zoom != test.expected || r.viewX != 0 || r.viewY != 0 || !r.fitted {
+			t.Errorf("%dx%d board in a %dx%d %v terminal: expected zoom %d at 0,0, got zoom %d at %d,%d",
+				test.width, test.height, test.cols, test.rows, test.mode, test.expected, r.zoom, r.viewX, r.viewY)
+		}
+	}
+}
+
+func TestClampView(t *testing.T) {
+	tests := []struct {
+		width, height int
+		viewX, viewY  int
+		expectedX     int
+		expectedY     int
+	}{
+		{100, 100, 10, 10, 10, 10},
+		{100, 100, 500, -3, 80, 0},
+		{100, 100, -3, 500, 0, 92},
+		{10, 4, 5, 5, 0, 0},
+	}
+	for _, test := range tests {
+		r, _, _, _ := newTestRenderer(test.width, test.height, 20, 5, HalfBlock)
+		r.viewX, r.viewY = test.viewX, test.viewY
+		r.clampView()
+		if r.viewX != test.expectedX || r.viewY != test.expectedY {
+			t.Errorf("%dx%d board viewed from %d,%d: expected %d,%d, got %d,%d",
+				test.width, test.height, test.viewX, test.viewY, test.expectedX, test.expectedY, r.viewX, r.viewY)
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	r, out, cols, _ := newTestRenderer(2, 2, 4, 2, HalfBlock, util.Cell{X: 0, Y: 0}, util.Cell{X: 1, Y: 1})
+	if err := r.Draw(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\x1b[2J\x1b[H▀▄  \x1b[K\r\n\x1b[7m Tur\x1b[0m\x1b[K"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	r.Draw()
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing to be drawn when nothing has changed, got %q", out.String())
+	}
+
+	r.HandleEvent(gol.CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: 1, Y: 0}})
+	r.HandleEvent(gol.TurnComplete{CompletedTurns: 1})
+	r.Draw()
+	expected = "\x1b[H▀█  \x1b[K\r\n\x1b[7m Tur\x1b[0m\x1b[K"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	// The screen is cleared when the terminal is resized
+	out.Reset()
+	*cols = 3
+	r.Draw()
+	expected = "\x1b[2J\x1b[H▀█ \x1b[K\r\n\x1b[7m Tu\x1b[0m\x1b[K"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestHandleKey(t *testing.T) {
+	r, _, _, _ := newTestRenderer(100, 100, 20, 5, HalfBlock)
+	for _, key := range []rune{'p', 's', 'q', 'k', 'n', '5'} {
+		if gameKey, ok := r.HandleKey(key); !ok || gameKey != key {
+			t.Errorf("Expected %q to be passed on to the game, got %q and %v", key, gameKey, ok)
+		}
+	}
+	if _, ok := r.HandleKey('x'); ok {
+		t.Error("Expected x not to be passed on to the game")
+	}
+
+	// The view is 20x8 cells, and moves a quarter of that
+	r.HandleKey(control.KeyRight)
+	r.HandleKey(control.KeyDown)
+	if r.viewX != 5 || r.viewY != 2 {
+		t.Errorf("Expected the view to move to 5,2, got %d,%d", r.viewX, r.viewY)
+	}
+	r.HandleKey(control.KeyLeft)
+	r.HandleKey(control.KeyLeft)
+	if r.viewX != 0 {
+		t.Errorf("Expected the view to stop at the edge of the board, got %d", r.viewX)
+	}
+
+	r.HandleKey('-')
+	if r.zoom != 1 {
+		t.Errorf("Expected - to zoom out, got zoom %d", r.zoom)
+	}
+	r.HandleKey('+')
+	if r.zoom != 0 {
+		t.Errorf("Expected + to zoom in, got zoom %d", r.zoom)
+	}
+	r.HandleKey('f')
+	if r.zoom != 4 || !r.fitted {
+		t.Errorf("Expected f to fit the board at zoom 4, got zoom %d", r.zoom)
+	}
+	r.HandleKey('m')
+	if r.mode != Braille || r.zoom != 3 {
+		t.Errorf("Expected m to switch to braille and fit the board at zoom 3, got %v at zoom %d", r.mode, r.zoom)
+	}
+}
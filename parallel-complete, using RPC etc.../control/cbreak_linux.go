@@ -0,0 +1,28 @@
+//go:build linux
+// +build linux
+
+package control
+
+import "golang.org/x/sys/unix"
+
+// Turn off line buffering and echo so keys are read as soon as they are pressed
+// Unlike raw mode, output processing and signals are left alone so logs and Ctrl-C still work
+func makeCbreak(fd int) (restore func() error, err error) {
+	previous, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+
+	cbreak := *previous
+	cbreak.Lflag &^= unix.ICANON | unix.ECHO
+	cbreak.Cc[unix.VMIN] = 1
+	cbreak.Cc[unix.VTIME] = 0
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, &cbreak)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return unix.IoctlSetTermios(fd, unix.TCSETS, previous)
+	}, nil
+}
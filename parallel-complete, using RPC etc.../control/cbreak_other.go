@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package control
+
+import "golang.org/x/term"
+
+// Put the terminal in raw mode so keys are read as soon as they are pressed
+// Ctrl-C no longer sends a signal in raw mode, so ReadTerminal handles it itself
+func makeCbreak(fd int) (restore func() error, err error) {
+	previous, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		return term.Restore(fd, previous)
+	}, nil
+}
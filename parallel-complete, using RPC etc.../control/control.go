@@ -0,0 +1,67 @@
+package control
+
+import (
+	"strings"
+)
+
+// This package sends keypresses to a running controller without SDL,
+// either from the terminal or from scripts through a control socket
+
+// Commands maps the name of each command to the key that does it
+// step, jump and speed use a count typed before their key, e.g. 50n steps 50 turns, see ParseCommandLine
+var Commands = map[string]rune{
+	"pause":     'p',
+	"save":      's',
+	"quit":      'q',
+	"kill":      'k',
+	"randomise": 'r',
+	"step":      'n',
+	"jump":      'j',
+	"speed":     't',
+}
+
+// ParseCommand converts a command name (e.g. "pause") or key (e.g. "p") into a key
+func ParseCommand(s string) (rune, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if key, ok := Commands[s]; ok {
+		return key, true
+	}
+	for _, key := range Commands {
+		if s == string(key) {
+			return key, true
+		}
+	}
+	return 0, false
+}
+
+// ParseCommandLine converts a command with an optional count into the keys to send for it
+// The count can come after a command name (e.g. "step 50") or before a key (e.g. "50n")
+func ParseCommandLine(s string) ([]rune, bool) {
+	var command, count string
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		command = strings.TrimLeft(fields[0], "0123456789")
+		count = fields[0][:len(fields[0])-len(command)]
+	case 2:
+		command, count = fields[0], fields[1]
+	default:
+		return nil, false
+	}
+	for _, digit := range count {
+		if !IsCountKey(digit) {
+			return nil, false
+		}
+	}
+
+	key, ok := ParseCommand(command)
+	if !ok {
+		return nil, false
+	}
+	return append([]rune(count), key), true
+}
+
+// IsCountKey returns whether a key is a digit of the count typed before a command
+func IsCountKey(key rune) bool {
+	return key >= '0' && key <= '9'
+}
@@ -0,0 +1,55 @@
+package control
+
+import "testing"
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+		ok       bool
+	}{
+		{"pause", "p", true},
+		{"p", "p", true},
+		{" Save ", "s", true},
+		{"step", "n", true},
+		{"step 50", "50n", true},
+		{"50n", "50n", true},
+		{"jump 100", "100j", true},
+		{"100j", "100j", true},
+		{"speed 0", "0t", true},
+		{"10 t", "", false},
+		{"50", "", false},
+		{"", "", false},
+		{"unknown", "", false},
+		{"x", "", false},
+		{"50x", "", false},
+		{"step50", "", false},
+		{"n50", "", false},
+		{"step 5a", "", false},
+		{"step -5", "", false},
+		{"step 50 60", "", false},
+		{"50 step", "", false},
+	}
+	for _, test := range tests {
+		keys, ok := ParseCommandLine(test.line)
+		if ok != test.ok || string(keys) != test.expected {
+			t.Errorf("%q: expected %q and %v, got %q and %v", test.line, test.expected, test.ok, string(keys), ok)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	for name, key := range Commands {
+		if parsed, ok := ParseCommand(name); !ok || parsed != key {
+			t.Errorf("%q: expected %q, got %q", name, key, parsed)
+		}
+		if parsed, ok := ParseCommand(string(key)); !ok || parsed != key {
+			t.Errorf("%q: expected %q, got %q", string(key), key, parsed)
+		}
+	}
+	for _, s := range []string{"", "5", "pausee", "P P"} {
+		if _, ok := ParseCommand(s); ok {
+			t.Errorf("Expected %q not to be a command", s)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// How long a command waits for the controller to take it before giving up
+const commandTimeout = time.Second
+
+// ListenSocket starts a unix socket at path which sends commands to keyPresses
+// Each line sent to the socket is a command name or key (e.g. "pause" or "p") with an optional count (e.g. "step 50" or "50n"),
+// and is answered with "ok" or "error: " followed by the reason
+// e.g. echo save | nc -U gol.sock
+// The socket is removed when the returned listener is closed
+func ListenSocket(path string, keyPresses chan<- rune) (net.Listener, error) {
+	// Remove a socket left behind by a previous run, so we can listen again
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleConnection(conn, keyPresses)
+		}
+	}()
+	return listener, nil
+}
+
+// Handle the commands sent by one client of the control socket
+func handleConnection(conn net.Conn, keyPresses chan<- rune) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			continue
+		}
+		keys, ok := ParseCommandLine(scanner.Text())
+		if !ok {
+			fmt.Fprintf(conn, "error: unknown command %q\n", scanner.Text())
+			continue
+		}
+
+		err := sendKeys(keys, keyPresses)
+		if err != nil {
+			fmt.Fprintln(conn, "error:", err)
+			continue
+		}
+		fmt.Fprintln(conn, "ok")
+	}
+}
+
+// Send the keys for a command, giving up if the controller doesn't take one of them
+func sendKeys(keys []rune, keyPresses chan<- rune) error {
+	timeout := time.After(commandTimeout)
+	for _, key := range keys {
+		select {
+		case keyPresses <- key:
+		case <-timeout:
+			return errors.New("the game is not taking commands")
+		}
+	}
+	return nil
+}
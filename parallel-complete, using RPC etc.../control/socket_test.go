@@ -0,0 +1,89 @@
+package control
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSocket sends commands through the control socket and checks the keys and replies
+func TestSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gol.sock")
+
+	keyPresses := make(chan rune, 10)
+	listener, err := ListenSocket(path, keyPresses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies := bufio.NewScanner(conn)
+	send := func(line string) string {
+		_, err := conn.Write([]byte(line + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !replies.Scan() {
+			t.Fatalf("No reply to %q: %v", line, replies.Err())
+		}
+		return replies.Text()
+	}
+
+	tests := []struct {
+		line  string
+		reply string
+		keys  string
+	}{
+		{"pause", "ok", "p"},
+		{"step 50", "ok", "50n"},
+		{"12j", "ok", "12j"},
+		{"dance", `error: unknown command "dance"`, ""},
+		{"step fifty", `error: unknown command "step fifty"`, ""},
+	}
+	for _, test := range tests {
+		if reply := send(test.line); reply != test.reply {
+			t.Errorf("%q: expected the reply %q, got %q", test.line, test.reply, reply)
+		}
+		keys := ""
+		for len(keys) < len(test.keys) {
+			keys += string(<-keyPresses)
+		}
+		if keys != test.keys || len(keyPresses) != 0 {
+			t.Errorf("%q: expected the keys %q, got %q with %d more", test.line, test.keys, keys, len(keyPresses))
+		}
+	}
+
+	// Nothing is reading the keys once the channel is full, so the command times out
+	for len(keyPresses) < cap(keyPresses) {
+		keyPresses <- 'x'
+	}
+	start := time.Now()
+	if reply := send("save"); !strings.HasPrefix(reply, "error: ") {
+		t.Errorf("Expected an error when the game isn't taking commands, got %q", reply)
+	}
+	if waited := time.Since(start); waited < commandTimeout {
+		t.Errorf("Expected the command to wait %v for the game, it waited %v", commandTimeout, waited)
+	}
+
+	// A socket left behind is replaced
+	listener.Close()
+	listener, err = ListenSocket(path, keyPresses)
+	if err != nil {
+		t.Fatalf("Expected to listen again on %s: %v", path, err)
+	}
+}
@@ -0,0 +1,121 @@
+package control
+
+import (
+	"bufio"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ctrlC is the byte read when Ctrl-C is pressed and the terminal doesn't turn it into a signal
+const ctrlC = 3
+
+// escape starts the sequence of bytes sent for keys without a character of their own, like arrow keys
+const escape = 0x1b
+
+// Keys which don't have a character of their own are sent as these runes
+const (
+	KeyUp rune = 0xF700 + iota
+	KeyDown
+	KeyLeft
+	KeyRight
+)
+
+// ReadTerminal sends the game's keys pressed in the terminal to keyPresses, along with the digits of counts
+// Stdin is switched out of line mode so keys are read as soon as they are pressed
+// The returned function puts the terminal back how it was, and must be called before exiting
+// If stdin isn't a terminal nothing is read and restore does nothing
+func ReadTerminal(keyPresses chan<- rune) (restore func(), err error) {
+	keys := make(chan rune, 10)
+	restore, err = ReadTerminalKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for key := range keys {
+			if IsCountKey(key) {
+				keyPresses <- key
+			} else if command, ok := ParseCommand(string(key)); ok {
+				keyPresses <- command
+			}
+		}
+	}()
+	return restore, nil
+}
+
+// ReadTerminalKeys is like ReadTerminal, but sends every key pressed so callers can handle their own keys
+// Arrow keys are sent as KeyUp, KeyDown, KeyLeft and KeyRight
+// Ctrl-C is sent as 'q' if the terminal doesn't turn it into a signal, so the game quits cleanly
+func ReadTerminalKeys(keys chan<- rune) (restore func(), err error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return func() {}, nil
+	}
+
+	restoreTerminal, err := makeCbreak(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	go readKeys(bufio.NewReader(os.Stdin), keys)
+	return func() { restoreTerminal() }, nil
+}
+
+// Send the keys read from the terminal until it can't be read any more
+// Escape sequences are read whole and dropped, unless they are arrow keys, so their bytes are never sent as keys
+// ESC followed by anything else, like Alt and a key, is sent as just the key
+func readKeys(reader *bufio.Reader, keys chan<- rune) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		if b == escape {
+			b, err = reader.ReadByte()
+			if err != nil {
+				return
+			}
+			if b == '[' || b == 'O' {
+				if key, ok := readSequence(reader, b); ok {
+					keys <- key
+				}
+				continue
+			}
+		}
+		switch {
+		case b == ctrlC:
+			keys <- 'q'
+		case b == escape:
+			// Escape pressed twice isn't a key either
+		case b < 0x80:
+			keys <- rune(b)
+		}
+	}
+}
+
+// Read the rest of an escape sequence after ESC and its introducer, returning the key if it is an arrow key
+// CSI sequences (ESC [) have any number of parameter bytes followed by a final byte from @ to ~,
+// e.g. ESC [ A is up and ESC [ 5 ~ is PageUp
+// SS3 sequences (ESC O) have just a final byte, and are used for arrow keys by some terminals
+func readSequence(reader *bufio.Reader, introducer byte) (rune, bool) {
+	parameters := 0
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, false
+		}
+		if b >= '@' && b <= '~' {
+			// Arrow keys with modifiers have parameters, e.g. ESC [ 1 ; 5 A for Ctrl-Up, which aren't used
+			if parameters == 0 && b >= 'A' && b <= 'D' {
+				return []rune{KeyUp, KeyDown, KeyRight, KeyLeft}[b-'A'], true
+			}
+			return 0, false
+		}
+		// Only CSI sequences have parameter and intermediate bytes, anything else means the sequence was cut short
+		if introducer != '[' || b < 0x20 || b > 0x3f {
+			return 0, false
+		}
+		parameters++
+	}
+}
@@ -0,0 +1,43 @@
+package control
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// TestReadKeys checks arrow keys are read from their escape sequences and every other sequence is dropped whole
+func TestReadKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []rune
+	}{
+		{"plain keys", "ps5n", []rune("ps5n")},
+		{"ctrl-c", "p\x03", []rune{'p', 'q'}},
+		{"arrows", "\x1b[A\x1b[B\x1b[C\x1b[D", []rune{KeyUp, KeyDown, KeyRight, KeyLeft}},
+		{"ss3 arrows", "\x1bOA\x1bOD", []rune{KeyUp, KeyLeft}},
+		{"page up", "\x1b[5~p", []rune{'p'}},
+		{"page down between keys", "s\x1b[6~p", []rune{'s', 'p'}},
+		{"ctrl-up", "\x1b[1;5Ap", []rune{'p'}},
+		{"function key", "\x1bOPq", []rune{'q'}},
+		{"alt and a key", "\x1bp", []rune{'p'}},
+		{"escape twice", "\x1b\x1bp", []rune{'p'}},
+		{"lone escape", "\x1b", nil},
+		{"cut short", "\x1b[5", nil},
+		{"not ascii", "\xc3\xa9p", []rune{'p'}},
+	}
+	for _, test := range tests {
+		keys := make(chan rune, 20)
+		readKeys(bufio.NewReader(strings.NewReader(test.input)), keys)
+		close(keys)
+
+		var received []rune
+		for key := range keys {
+			received = append(received, key)
+		}
+		if string(received) != string(test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, received)
+		}
+	}
+}
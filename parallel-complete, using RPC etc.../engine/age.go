@@ -0,0 +1,64 @@
+package engine
+
+import "math"
+
+// This file keeps track of how long each cell has been alive or dead, so saved boards can show
+// which parts of the board are stable and which are chaotic
+// The ages are worked out by comparing the board after each turn, so every turn must be calculated
+
+// Ages counts the turns each cell has been alive for, or dead for since it was last alive
+// A positive age is the number of turns a cell has been alive, 1 if it was born on the last turn
+// A negative age is minus the number of turns since the cell died
+// A cell which hasn't been alive since tracking started has an age of 0
+// Ages stop changing when they reach the limits of an int16
+type Ages struct {
+	cells [][]int16
+}
+
+// NewAges starts tracking the ages of the cells on a board, with every alive cell just born
+func NewAges(board [][]bool) *Ages {
+	cells := make([][]int16, len(board))
+	for y, row := range board {
+		cells[y] = make([]int16, len(row))
+		for x, alive := range row {
+			if alive {
+				cells[y][x] = 1
+			}
+		}
+	}
+	return &Ages{cells: cells}
+}
+
+// Update ages every cell by a turn, given the board after the turn
+func (a *Ages) Update(board [][]bool) {
+	for y, row := range board {
+		ages := a.cells[y]
+		for x, alive := range row {
+			age := ages[x]
+			switch {
+			case alive && age > 0:
+				if age < math.MaxInt16 {
+					ages[x]++
+				}
+			case alive:
+				ages[x] = 1
+			case age > 0:
+				ages[x] = -1
+			case age < 0:
+				if age > math.MinInt16 {
+					ages[x]--
+				}
+			}
+		}
+	}
+}
+
+// Slice returns a copy of the ages, row by row
+func (a *Ages) Slice() [][]int16 {
+	cells := make([][]int16, len(a.cells))
+	for y, row := range a.cells {
+		cells[y] = make([]int16, len(row))
+		copy(cells[y], row)
+	}
+	return cells
+}
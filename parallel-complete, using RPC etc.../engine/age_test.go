@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAgesUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		age      int16
+		alive    bool
+		expected int16
+	}{
+		{"born", 0, true, 1},
+		{"survives", 1, true, 2},
+		{"dies", 5, false, -1},
+		{"trail fades", -1, false, -2},
+		{"born again", -7, true, 1},
+		{"never alive", 0, false, 0},
+		{"oldest alive", math.MaxInt16, true, math.MaxInt16},
+		{"longest dead", math.MinInt16, false, math.MinInt16},
+		{"oldest dies", math.MaxInt16, false, -1},
+		{"longest dead is born", math.MinInt16, true, 1},
+	}
+	ages := &Ages{cells: [][]int16{make([]int16, len(tests))}}
+	board := [][]bool{make([]bool, len(tests))}
+	for x, test := range tests {
+		ages.cells[0][x] = test.age
+		board[0][x] = test.alive
+	}
+	ages.Update(board)
+	for x, test := range tests {
+		if ages.cells[0][x] != test.expected {
+			t.Errorf("%s: expected %d, got %d", test.name, test.expected, ages.cells[0][x])
+		}
+	}
+}
+
+// TestAgesTurns follows a blinker, whose end cells die and are born every turn while the middle one survives
+func TestAgesTurns(t *testing.T) {
+	vertical := [][]bool{
+		{false, true, false},
+		{false, true, false},
+		{false, true, false},
+	}
+	horizontal := [][]bool{
+		{false, false, false},
+		{true, true, true},
+		{false, false, false},
+	}
+	ages := NewAges(vertical)
+	if ages.cells[1][1] != 1 || ages.cells[0][1] != 1 || ages.cells[0][0] != 0 {
+		t.Fatalf("Expected alive cells to start newborn, got %v", ages.cells)
+	}
+	for turn := 1; turn <= 4; turn++ {
+		board := horizontal
+		if turn%2 == 0 {
+			board = vertical
+		}
+		ages.Update(board)
+		if ages.cells[1][1] != int16(turn+1) {
+			t.Errorf("Turn %d: expected the middle to be %d turns old, got %d", turn, turn+1, ages.cells[1][1])
+		}
+		for _, end := range [][2]int{{0, 1}, {1, 0}} {
+			age := ages.cells[end[0]][end[1]]
+			if board[end[0]][end[1]] && age != 1 || !board[end[0]][end[1]] && age != -1 {
+				t.Errorf("Turn %d: expected the end at %v to be born or to have just died, got %d", turn, end, age)
+			}
+		}
+		if ages.cells[0][0] != 0 {
+			t.Errorf("Turn %d: expected a corner which was never alive to stay 0, got %d", turn, ages.cells[0][0])
+		}
+	}
+
+	// Slice is a copy, so changing it doesn't change the ages
+	slice := ages.Slice()
+	slice[1][1] = 100
+	if ages.cells[1][1] == 100 {
+		t.Error("Expected Slice to return a copy")
+	}
+}
@@ -0,0 +1,154 @@
+// Package engine contains the Game of Life logic shared by the workers and the server
+package engine
+
+import (
+	"sync"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// DoTurn calculates the next turn for the fragment in a halo, using a number of threads
+// The rows are packed into 64 bit words so 64 cells are calculated at once (see packed.go)
+// Return a fragment of the board with the next turn's cells
+func DoTurn(halo stubs.Halo, threads int, rule stubs.Rule, topology stubs.Topology) stubs.Fragment {
+	width := halo.BitBoard.RowLength
+	board := PackBytes(halo.BitBoard.Bytes.Decode(), halo.BitBoard.NumRows, width)
+	height := halo.EndPtr - halo.StartPtr
+	newBoard := NewPackedBoard(height, width)
+
+	if threads > height {
+		threads = height
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	var wg sync.WaitGroup
+
+	fragHeight := height / threads
+	for i := 0; i < threads; i++ {
+		start := i * fragHeight
+		end := (i + 1) * fragHeight
+		if i == threads-1 {
+			end = height
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			for row := start; row < end; row++ {
+				y := row + halo.Offset
+				StepRow(board.Rows[y-1], board.Rows[y], board.Rows[y+1], newBoard.Rows[row], width, rule, topology.WrapsColumns())
+			}
+			wg.Done()
+		}(start, end)
+	}
+
+	// Wait for all threads to finish
+	wg.Wait()
+
+	return stubs.Fragment{
+		StartRow: halo.StartPtr,
+		EndRow:   halo.EndPtr,
+		BitBoard: stubs.BitBoardFromSlice(newBoard.Slice(), height, width),
+	}
+}
+
+// DoTurnByCell calculates the next turn in the same way as DoTurn, but one cell at a time
+// It is much slower, and is kept to check and benchmark the packed kernel against
+func DoTurnByCell(halo stubs.Halo, threads int, rule stubs.Rule, topology stubs.Topology) (boardFragment stubs.Fragment) {
+	width := halo.BitBoard.RowLength
+	board := halo.BitBoard.Bytes.Decode()
+	height := halo.EndPtr - halo.StartPtr
+	newBoard := make([][]bool, height)
+
+	
+	if threads > height {
+		threads = height
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	var wg sync.WaitGroup
+	
+	fragHeight := height / threads
+	for i := 0; i < threads; i++ {
+		
+		start := i * fragHeight
+		end := (i + 1) * fragHeight
+		if i == threads-1 {
+			end = height
+		}
+		
+		wg.Add(1)
+		
+		go updateRegion(start, end, halo, newBoard, width, board, rule, topology, &wg)
+	}
+
+	// Wait for all threads to finish
+	wg.Wait()
+
+	
+	boardFragment = stubs.Fragment{
+		StartRow: halo.StartPtr,
+		EndRow:   halo.EndPtr,
+		BitBoard: stubs.BitBoardFromSlice(newBoard, halo.EndPtr-halo.StartPtr, width), 
+	}
+	return boardFragment
+}
+
+// Calculate the next cell state for all cells within bounds
+func updateRegion(start, end int, halo stubs.Halo, newBoard [][]bool, width int, board []byte, rule stubs.Rule, topology stubs.Topology, wg *sync.WaitGroup) {
+	
+	for row := start; row < end; row++ {
+		newBoard[row] = make([]bool, width)
+		for col := 0; col < width; col++ {
+			
+			newCell := nextCellState(col, row+halo.Offset, board, halo.BitBoard.NumRows, halo.BitBoard.RowLength, rule, topology)
+			
+			newBoard[row][col] = newCell
+		}
+	}
+	wg.Done()
+}
+
+// Calculate the next cell state according to the game's rule
+// Returns a bool with the next state of the cell
+func nextCellState(x int, y int, board []byte, bHeight, bWidth int, rule stubs.Rule, topology stubs.Topology) bool {
+	
+	adj := countAliveNeighbours(x, y, board, bHeight, bWidth, topology)
+
+	// The rule decides whether the cell is born, survives or dies
+	return rule.Next(stubs.GetBitArrayCell(board, bHeight, bWidth, y, x), adj)
+}
+
+// Count how many alive neighbours a cell has
+// This will wrap around the left and right edges if the topology joins them
+// The halo always contains the rows above and below, so rows never need to wrap
+func countAliveNeighbours(x int, y int, board []byte, height, width int, topology stubs.Topology) int {
+	numNeighbours := 0
+
+	
+	for _x := -1; _x < 2; _x++ {
+		for _y := -1; _y < 2; _y++ {
+			
+			if _x == 0 && _y == 0 {
+				continue
+			}
+
+			wrapX := x + _x
+			
+			if wrapX == -1 || wrapX == width {
+				// Cells off an edge which isn't joined are dead
+				if !topology.WrapsColumns() {
+					continue
+				}
+				wrapX = (wrapX + width) % width
+			}
+
+			v := stubs.GetBitArrayCell(board, height, width, y+_y, wrapX)
+			if v == true {
+				numNeighbours++
+			}
+		}
+	}
+
+	return numNeighbours
+}
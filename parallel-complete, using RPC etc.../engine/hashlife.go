@@ -0,0 +1,296 @@
+package engine
+
+import (
+	"errors"
+	"strconv"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains the HashLife engine, which can calculate huge numbers of turns quickly
+// The board is stored as a quadtree where identical squares ("macrocells") are the same node,
+// and the result of running a node forward is remembered, so repeated patterns are only calculated once
+// A torus behaves the same as the board tiled forever, so the board is tiled until it is big enough to run
+
+// Once this many nodes exist the caches are cleared so memory doesn't run out, see join
+const hashLifeMaxNodes = 1 << 22
+
+// node is a square of 2^level by 2^level cells
+// Level 0 nodes are single cells, others are made of four nodes one level down
+type node struct {
+	level          int
+	nw, ne, sw, se *node
+	alive          bool
+}
+
+// quad is used to find a node from its four children
+type quad struct {
+	nw, ne, sw, se *node
+}
+
+// resultKey is used to find the result of running a node forward 2^step turns
+type resultKey struct {
+	node *node
+	step int
+}
+
+// HashLife runs a game on a torus using the HashLife algorithm
+// The height and width of the board must be powers of two
+type HashLife struct {
+	rule          stubs.Rule
+	height, width int
+
+	// The board tiled into a square of 2^sizeLevel by 2^sizeLevel cells
+	root      *node
+	sizeLevel int
+
+	dead, alive *node
+	nodes       map[quad]*node
+	results     map[resultKey]*node
+	maxNodes    int
+}
+
+// CheckHashLife returns an error if a board can't be run with HashLife
+func CheckHashLife(height, width int, topology stubs.Topology) error {
+	if topology != stubs.Torus {
+		return errors.New("HashLife only supports the torus topology")
+	}
+	if !powerOfTwo(height) || !powerOfTwo(width) {
+		return errors.New("HashLife needs a power of two height and width, not " + strconv.Itoa(width) + "x" + strconv.Itoa(height))
+	}
+	return nil
+}
+
+// NewHashLife creates a HashLife engine starting with a board on a torus
+func NewHashLife(board [][]bool, height, width int, rule stubs.Rule) (*HashLife, error) {
+	err := CheckHashLife(height, width, stubs.Torus)
+	if err != nil {
+		return nil, err
+	}
+	h := &HashLife{rule: rule, height: height, width: width, maxNodes: hashLifeMaxNodes}
+	h.SetBoard(board)
+	return h, nil
+}
+
+// SetBoard replaces the cells of the board
+func (h *HashLife) SetBoard(board [][]bool) {
+	h.nodes = make(map[quad]*node)
+	h.results = make(map[resultKey]*node)
+	h.dead = &node{level: 0}
+	h.alive = &node{level: 0, alive: true}
+
+	size := h.height
+	if h.width > size {
+		size = h.width
+	}
+	h.sizeLevel = 0
+	for 1<<uint(h.sizeLevel) < size {
+		h.sizeLevel++
+	}
+	h.root = h.build(board, h.sizeLevel, 0, 0)
+}
+
+// Board returns the cells of the board
+func (h *HashLife) Board() [][]bool {
+	board := make([][]bool, h.height)
+	for row := range board {
+		board[row] = make([]bool, h.width)
+	}
+	h.fill(h.root, 0, 0, board)
+	return board
+}
+
+// Advance runs the board forward exactly a number of turns
+// The turns are split into powers of two, each of which is calculated in one go
+func (h *HashLife) Advance(turns int) {
+	for step := 62; step >= 0; step-- {
+		if turns&(1<<uint(step)) != 0 {
+			h.jump(step)
+		}
+	}
+}
+
+// Run the board forward 2^step turns
+func (h *HashLife) jump(step int) {
+	// A node at a level runs forward at most 2^(level-2) turns, giving the centre half of it
+	// The top left of the centre must also line up with the board, which happens once it is 4 boards wide
+	level := step
+	if h.sizeLevel > level {
+		level = h.sizeLevel
+	}
+	level += 2
+
+	result := h.result(h.tile(level), step)
+	// Every tile of the result is the same, so take the top left one
+	for result.level > h.sizeLevel {
+		result = result.nw
+	}
+	h.root = result
+}
+
+// Get the board tiled into a node of a level
+func (h *HashLife) tile(level int) *node {
+	if level == h.sizeLevel {
+		return h.root
+	}
+	t := h.tile(level - 1)
+	return h.join(t, t, t, t)
+}
+
+// Find the node made of four children, creating it if it doesn't exist yet
+// The caches are cleared once they hold maxNodes nodes, even part way through a jump
+// Nodes still in use stay correct, they just aren't shared with nodes made after the clear
+func (h *HashLife) join(nw, ne, sw, se *node) *node {
+	key := quad{nw, ne, sw, se}
+	if n, exists := h.nodes[key]; exists {
+		return n
+	}
+	if len(h.nodes) >= h.maxNodes {
+		h.nodes = make(map[quad]*node)
+		h.results = make(map[resultKey]*node)
+	}
+	n := &node{level: nw.level + 1, nw: nw, ne: ne, sw: sw, se: se}
+	h.nodes[key] = n
+	return n
+}
+
+// Get the centre node one level down from a node
+func (h *HashLife) centre(n *node) *node {
+	return h.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+// Get the centre of a node after running it forward 2^step turns
+// step can be at most the level of the node minus 2
+func (h *HashLife) result(n *node, step int) *node {
+	key := resultKey{n, step}
+	if r, exists := h.results[key]; exists {
+		return r
+	}
+
+	var r *node
+	if n.level == 2 {
+		r = h.baseResult(n)
+	} else {
+		// Split the node into nine overlapping nodes one level down
+		n00 := n.nw
+		n01 := h.join(n.nw.ne, n.ne.nw, n.nw.se, n.ne.sw)
+		n02 := n.ne
+		n10 := h.join(n.nw.sw, n.nw.se, n.sw.nw, n.sw.ne)
+		n11 := h.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+		n12 := h.join(n.ne.sw, n.ne.se, n.se.nw, n.se.ne)
+		n20 := n.sw
+		n21 := h.join(n.sw.ne, n.se.nw, n.sw.se, n.se.sw)
+		n22 := n.se
+
+		// For the biggest step both halves run forward, otherwise only the second half does
+		first := h.centre
+		if step == n.level-2 {
+			first = func(m *node) *node {
+				return h.result(m, step-1)
+			}
+		}
+		second := step
+		if second > n.level-3 {
+			second = n.level - 3
+		}
+
+		c00, c01, c02 := first(n00), first(n01), first(n02)
+		c10, c11, c12 := first(n10), first(n11), first(n12)
+		c20, c21, c22 := first(n20), first(n21), first(n22)
+
+		r = h.join(
+			h.result(h.join(c00, c01, c10, c11), second),
+			h.result(h.join(c01, c02, c11, c12), second),
+			h.result(h.join(c10, c11, c20, c21), second),
+			h.result(h.join(c11, c12, c21, c22), second),
+		)
+	}
+
+	h.results[key] = r
+	return r
+}
+
+// Calculate the centre 2x2 cells of a 4x4 node after one turn
+func (h *HashLife) baseResult(n *node) *node {
+	var cells [4][4]bool
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			cells[row][col] = cell(n, row, col)
+		}
+	}
+
+	next := [4]*node{}
+	for i := 0; i < 4; i++ {
+		row, col := 1+i/2, 1+i%2
+		neighbours := 0
+		for y := row - 1; y <= row+1; y++ {
+			for x := col - 1; x <= col+1; x++ {
+				if (y != row || x != col) && cells[y][x] {
+					neighbours++
+				}
+			}
+		}
+		next[i] = h.dead
+		if h.rule.Next(cells[row][col], neighbours) {
+			next[i] = h.alive
+		}
+	}
+	return h.join(next[0], next[1], next[2], next[3])
+}
+
+// Get a single cell from a node
+func cell(n *node, row, col int) bool {
+	for n.level > 0 {
+		half := 1 << uint(n.level-1)
+		switch {
+		case row < half && col < half:
+			n = n.nw
+		case row < half:
+			n, col = n.ne, col-half
+		case col < half:
+			n, row = n.sw, row-half
+		default:
+			n, row, col = n.se, row-half, col-half
+		}
+	}
+	return n.alive
+}
+
+// Build the node of a level with its top left cell at (row, col) of the board tiled forever
+func (h *HashLife) build(board [][]bool, level, row, col int) *node {
+	if level == 0 {
+		if board[row%h.height][col%h.width] {
+			return h.alive
+		}
+		return h.dead
+	}
+	half := 1 << uint(level-1)
+	return h.join(
+		h.build(board, level-1, row, col),
+		h.build(board, level-1, row, col+half),
+		h.build(board, level-1, row+half, col),
+		h.build(board, level-1, row+half, col+half),
+	)
+}
+
+// Copy the cells of a node with its top left cell at (row, col) onto the board
+// Cells past the edge of the board are repeats of the tile and are skipped
+func (h *HashLife) fill(n *node, row, col int, board [][]bool) {
+	if row >= h.height || col >= h.width {
+		return
+	}
+	if n.level == 0 {
+		board[row][col] = n.alive
+		return
+	}
+	half := 1 << uint(n.level-1)
+	h.fill(n.nw, row, col, board)
+	h.fill(n.ne, row, col+half, board)
+	h.fill(n.sw, row+half, col, board)
+	h.fill(n.se, row+half, col+half, board)
+}
+
+// Check if a number is a power of two
+func powerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestHashLifeRandom compares HashLife with DoTurnByCell on random boards, including boards which aren't square,
+// jumping by numbers of turns which aren't powers of two
+func TestHashLifeRandom(t *testing.T) {
+	highLife, err := stubs.ParseRule("B36/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := rand.New(rand.NewSource(1))
+	sizes := [][2]int{{1, 1}, {2, 2}, {4, 8}, {16, 16}, {32, 8}, {64, 128}}
+	for _, rule := range []stubs.Rule{stubs.ConwayRule, highLife} {
+		for _, size := range sizes {
+			height, width := size[0], size[1]
+			t.Run(rule.String()+"/"+strconv.Itoa(width)+"x"+strconv.Itoa(height), func(t *testing.T) {
+				board := newBoard(height, width)
+				for row := range board {
+					for col := range board[row] {
+						board[row][col] = random.Intn(3) == 0
+					}
+				}
+				hashLife, err := NewHashLife(board, height, width, rule)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				turn := 0
+				for _, turns := range []int{0, 1, 3, 4, 27, 65} {
+					hashLife.Advance(turns)
+					for end := turn + turns; turn < end; turn++ {
+						board = referenceTurn(board, height, width, rule, stubs.Torus)
+					}
+					if !sameBoard(hashLife.Board(), board) {
+						t.Fatalf("Boards differ on turn %d", turn)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestHashLifeJump checks jumps far past what could be calculated a turn at a time, using patterns which repeat
+func TestHashLifeJump(t *testing.T) {
+	tests := []struct {
+		name          string
+		height, width int
+		turns         int
+		moved         int
+	}{
+		// A glider moves one cell every 4 turns, so after 4 times the board width it is back where it started
+		{"glider around the torus", 64, 64, 256, 0},
+		{"glider part way", 64, 32, 40, 10},
+		{"glider far away", 32, 32, 1 << 40, 0},
+	}
+	for _, test := range tests {
+		board := newBoard(test.height, test.width)
+		placeGlider(board, 5, 5)
+		hashLife, err := NewHashLife(board, test.height, test.width, stubs.ConwayRule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashLife.Advance(test.turns)
+
+		expected := newBoard(test.height, test.width)
+		placeGlider(expected, 5+test.moved, 5+test.moved)
+		if !sameBoard(hashLife.Board(), expected) {
+			t.Errorf("%s: expected the glider to have moved %d cells after %d turns", test.name, test.moved, test.turns)
+		}
+	}
+
+	// SetBoard starts again from a new board
+	board := newBoard(8, 8)
+	board[3][2], board[3][3], board[3][4] = true, true, true
+	hashLife, err := NewHashLife(newBoard(8, 8), 8, 8, stubs.ConwayRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashLife.SetBoard(board)
+	hashLife.Advance(1<<50 + 1)
+	if !sameBoard(hashLife.Board(), referenceTurn(board, 8, 8, stubs.ConwayRule, stubs.Torus)) {
+		t.Error("Expected a blinker to be on its other phase after an odd number of turns")
+	}
+}
+
+// TestHashLifeMaxNodes runs a random board with a tiny cap on the number of nodes, so the caches are cleared
+// part way through each jump, and checks the cap holds without changing the result
+func TestHashLifeMaxNodes(t *testing.T) {
+	const height, width, maxNodes = 32, 32, 4000
+	random := rand.New(rand.NewSource(1))
+	board := newBoard(height, width)
+	for row := range board {
+		for col := range board[row] {
+			board[row][col] = random.Intn(3) == 0
+		}
+	}
+	hashLife, err := NewHashLife(board, height, width, stubs.ConwayRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashLife.maxNodes = maxNodes
+	hashLife.SetBoard(board)
+
+	turn := 0
+	for _, turns := range []int{1, 16, 37, 64, 128} {
+		hashLife.Advance(turns)
+		for end := turn + turns; turn < end; turn++ {
+			board = referenceTurn(board, height, width, stubs.ConwayRule, stubs.Torus)
+		}
+		if len(hashLife.nodes) > maxNodes {
+			t.Errorf("Turn %d: expected at most %d nodes, there are %d", turn, maxNodes, len(hashLife.nodes))
+		}
+		if !sameBoard(hashLife.Board(), board) {
+			t.Fatalf("Boards differ on turn %d", turn)
+		}
+	}
+}
+
+func TestCheckHashLife(t *testing.T) {
+	tests := []struct {
+		height, width int
+		topology      stubs.Topology
+		ok            bool
+	}{
+		{512, 512, stubs.Torus, true},
+		{1, 64, stubs.Torus, true},
+		{48, 64, stubs.Torus, false},
+		{64, 100, stubs.Torus, false},
+		{64, 64, stubs.Plane, false},
+		{64, 64, stubs.Cylinder, false},
+		{64, 64, stubs.KleinBottle, false},
+	}
+	for _, test := range tests {
+		err := CheckHashLife(test.height, test.width, test.topology)
+		if (err == nil) != test.ok {
+			t.Errorf("%dx%d %v: expected ok to be %v, got %v", test.width, test.height, test.topology, test.ok, err)
+		}
+	}
+	if _, err := NewHashLife(newBoard(3, 4), 3, 4, stubs.ConwayRule); err == nil {
+		t.Error("Expected an error creating HashLife for a 4x3 board")
+	}
+}
@@ -0,0 +1,176 @@
+package engine
+
+import "uk.ac.bris.cs/gameoflife/stubs"
+
+// This file contains a Game of Life kernel which works on 64 cells at once
+// Each row is packed into 64 bit words, and the neighbours of every cell in a word
+// are added up together using bitwise adders, so cells are never looked at one by one
+
+// Number of cells packed into each word
+const wordSize = 64
+
+// PackedBoard stores a board with each row packed into 64 bit words
+// Cell (row, col) is bit col%64 of word col/64 in its row
+// Any bits past the width of the board are always 0
+type PackedBoard struct {
+	Height int
+	Width  int
+	Rows   [][]uint64
+}
+
+// NewPackedBoard creates a packed board with every cell dead
+func NewPackedBoard(height, width int) *PackedBoard {
+	words := (width + wordSize - 1) / wordSize
+	rows := make([][]uint64, height)
+	for row := range rows {
+		rows[row] = make([]uint64, words)
+	}
+	return &PackedBoard{Height: height, Width: width, Rows: rows}
+}
+
+// PackBytes packs a decoded bit array (see RLEBitArray.Decode) into a packed board
+func PackBytes(bytes []byte, height, width int) *PackedBoard {
+	board := NewPackedBoard(height, width)
+	for row := 0; row < height; row++ {
+		words := board.Rows[row]
+		bit := row * width
+		// When rows start on a byte boundary whole bytes can be copied, as both are least significant bit first
+		if width%8 == 0 {
+			for col := 0; col < width; col += 8 {
+				words[col/wordSize] |= uint64(bytes[(bit+col)/8]) << uint(col%wordSize)
+			}
+			continue
+		}
+		for col := 0; col < width; col++ {
+			if stubs.GetBitArrayCell(bytes, height, width, row, col) {
+				words[col/wordSize] |= 1 << uint(col%wordSize)
+			}
+		}
+	}
+	return board
+}
+
+// PackSlice packs a 2d board slice into a packed board
+func PackSlice(cells [][]bool, height, width int) *PackedBoard {
+	board := NewPackedBoard(height, width)
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			if cells[row][col] {
+				board.Rows[row][col/wordSize] |= 1 << uint(col%wordSize)
+			}
+		}
+	}
+	return board
+}
+
+// Slice unpacks a packed board back to a 2d board slice
+func (b *PackedBoard) Slice() [][]bool {
+	cells := make([][]bool, b.Height)
+	for row := 0; row < b.Height; row++ {
+		cells[row] = make([]bool, b.Width)
+		for col := 0; col < b.Width; col++ {
+			cells[row][col] = b.Rows[row][col/wordSize]&(1<<uint(col%wordSize)) != 0
+		}
+	}
+	return cells
+}
+
+// StepRow calculates the next state of a packed row from the rows above and below it
+// The new row is written to out, which must be the same length as row
+// If wrap is set the left and right edges of the board are joined
+func StepRow(up, row, down, out []uint64, width int, rule stubs.Rule, wrap bool) {
+	counts := newRuleCounts(rule)
+	for w := range row {
+		out[w] = stepWord(up, row, down, w, width, counts, wrap)
+	}
+}
+
+// ruleCounts lists which neighbour counts give an alive cell, so the rule is only looked at once per row
+type ruleCounts struct {
+	births, survivals [9]bool
+}
+
+// Work out which neighbour counts give an alive cell under a rule
+func newRuleCounts(rule stubs.Rule) *ruleCounts {
+	counts := new(ruleCounts)
+	for n := 0; n <= 8; n++ {
+		counts.births[n] = rule.Next(false, n)
+		counts.survivals[n] = rule.Next(true, n)
+	}
+	return counts
+}
+
+// Calculate the next state of word w of a packed row
+func stepWord(up, row, down []uint64, w int, width int, counts *ruleCounts, wrap bool) uint64 {
+	last := len(row) - 1
+	lastBit := uint((width - 1) % wordSize)
+
+	upWest, upEast := shiftRow(up, w, last, lastBit, wrap)
+	west, east := shiftRow(row, w, last, lastBit, wrap)
+	downWest, downEast := shiftRow(down, w, last, lastBit, wrap)
+
+	// Add up the 8 neighbours of every cell, giving a 4 bit count spread over count0-count3
+	sum0, carry0 := fullAdd(upWest, up[w], upEast)
+	sum1, carry1 := fullAdd(west, east, down[w])
+	sum2, carry2 := downWest^downEast, downWest&downEast
+	count0, carry3 := fullAdd(sum0, sum1, sum2)
+	twos, fours := fullAdd(carry0, carry1, carry2)
+	count1, carry4 := twos^carry3, twos&carry3
+	count2, count3 := fours^carry4, fours&carry4
+
+	alive := row[w]
+	next := uint64(0)
+	for n := 0; n <= 8; n++ {
+		if !counts.births[n] && !counts.survivals[n] {
+			continue
+		}
+		// Find the cells with exactly n neighbours
+		matches := countBit(count0, n&1) & countBit(count1, n&2) & countBit(count2, n&4) & countBit(count3, n&8)
+		if !counts.births[n] {
+			matches &= alive
+		} else if !counts.survivals[n] {
+			matches &^= alive
+		}
+		next |= matches
+	}
+
+	// Cells past the width of the board must stay dead
+	if w == last {
+		next &= ^uint64(0) >> (wordSize - 1 - lastBit)
+	}
+	return next
+}
+
+// Get a word of a row moved one cell along each way, so every bit holds
+// the cell to the west or the east of the cell that bit stands for
+// Cells off an edge which isn't joined are dead
+func shiftRow(row []uint64, w, last int, lastBit uint, wrap bool) (west, east uint64) {
+	west = row[w] << 1
+	if w > 0 {
+		west |= row[w-1] >> (wordSize - 1)
+	} else if wrap {
+		west |= (row[last] >> lastBit) & 1
+	}
+
+	east = row[w] >> 1
+	if w < last {
+		east |= row[w+1] << (wordSize - 1)
+	} else if wrap {
+		east |= (row[0] & 1) << lastBit
+	}
+	return
+}
+
+// Add three words bit by bit, giving the sum and carry bits
+func fullAdd(a, b, c uint64) (sum, carry uint64) {
+	partial := a ^ b
+	return partial ^ c, a&b | partial&c
+}
+
+// Get the cells where a bit of the neighbour count matches a bit of n
+func countBit(count uint64, set int) uint64 {
+	if set != 0 {
+		return count
+	}
+	return ^count
+}
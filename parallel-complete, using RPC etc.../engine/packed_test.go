@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestDoTurnPacked compares the packed kernel with DoTurnByCell on random boards,
+// with widths around the size of a word and every topology, for whole boards and strips of them
+func TestDoTurnPacked(t *testing.T) {
+	var rules []stubs.Rule
+	for _, notation := range []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678", "B012345678/S012345678"} {
+		rule, err := stubs.ParseRule(notation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+	random := rand.New(rand.NewSource(1))
+	sizes := [][2]int{{1, 1}, {3, 5}, {8, 63}, {8, 64}, {9, 65}, {4, 127}, {5, 128}, {6, 130}}
+	for _, rule := range rules {
+		for _, topology := range []stubs.Topology{stubs.Torus, stubs.Plane, stubs.Cylinder, stubs.KleinBottle} {
+			for _, size := range sizes {
+				height, width := size[0], size[1]
+				name := rule.String() + "/" + topology.String() + "/" + strconv.Itoa(width) + "x" + strconv.Itoa(height)
+				t.Run(name, func(t *testing.T) {
+					board := newBoard(height, width)
+					for row := range board {
+						for col := range board[row] {
+							board[row][col] = random.Intn(3) == 0
+						}
+					}
+
+					// The whole board, and a strip in the middle as a worker would be given
+					strips := [][2]int{{0, height}, {height / 3, height - height/3}}
+					for _, strip := range strips {
+						if strip[0] >= strip[1] {
+							continue
+						}
+						halo := boardHalo(board, strip[0], strip[1], height, width, topology)
+						for _, threads := range []int{1, 3} {
+							packed := DoTurn(halo, threads, rule, topology)
+							expected := DoTurnByCell(halo, 1, rule, topology)
+							if packed.StartRow != expected.StartRow || packed.EndRow != expected.EndRow ||
+								!sameBoard(packed.BitBoard.ToSlice(), expected.BitBoard.ToSlice()) {
+								t.Errorf("Rows %d to %d with %d threads differ from DoTurnByCell", strip[0], strip[1], threads)
+							}
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestStepRowPadding checks the bits past the width of the board stay dead, even when every cell comes alive
+func TestStepRowPadding(t *testing.T) {
+	everything, err := stubs.ParseRule("B012345678/S012345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, width := range []int{1, 63, 65, 100} {
+		for _, wrap := range []bool{true, false} {
+			board := NewPackedBoard(3, width)
+			out := make([]uint64, len(board.Rows[1]))
+			StepRow(board.Rows[0], board.Rows[1], board.Rows[2], out, width, everything, wrap)
+
+			last := len(out) - 1
+			spare := out[last] &^ (^uint64(0) >> uint(wordSize*len(out)-width))
+			if spare != 0 {
+				t.Errorf("%d wide, wrap %v: bits past the width are alive: %064b", width, wrap, out[last])
+			}
+			for col := 0; col < width; col++ {
+				if out[col/wordSize]&(1<<uint(col%wordSize)) == 0 {
+					t.Errorf("%d wide, wrap %v: cell %d is dead", width, wrap, col)
+					break
+				}
+			}
+		}
+	}
+}
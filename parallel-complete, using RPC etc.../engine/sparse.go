@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains the sparse engine, which only calculates the parts of the board that can change
+// The board is split into tiles, and if a tile and the tiles around it are the same as two turns ago,
+// the tile's next turn must be the same as it was two turns ago too, so it is skipped
+// This covers still lifes and blinkers, so boards which settle down get cheaper to run
+
+// Tiles are one packed word (64 cells) wide and this many rows high
+const sparseTileHeight = 16
+
+// Sparse runs a game using the sparse engine
+type Sparse struct {
+	// The board on this turn and the last turn
+	board, next *PackedBoard
+	rule        stubs.Rule
+	topology    stubs.Topology
+
+	tileRows, tileCols int
+	// Whether each tile is different to two turns ago, stored row by row
+	changed []bool
+	// Turns left where every tile must be calculated, as the last turn isn't known yet
+	fullTurns int
+	// The number of tiles calculated on the last turn
+	active int
+}
+
+// NewSparse creates a sparse engine starting with a board
+func NewSparse(board [][]bool, height, width int, rule stubs.Rule, topology stubs.Topology) *Sparse {
+	s := &Sparse{
+		board:    PackSlice(board, height, width),
+		next:     NewPackedBoard(height, width),
+		rule:     rule,
+		topology: topology,
+
+		tileRows: (height + sparseTileHeight - 1) / sparseTileHeight,
+		tileCols: (width + wordSize - 1) / wordSize,
+	}
+	s.changed = make([]bool, s.tileRows*s.tileCols)
+	s.markAllChanged()
+	return s
+}
+
+// SetBoard replaces the cells of the board
+func (s *Sparse) SetBoard(board [][]bool) {
+	s.board = PackSlice(board, s.board.Height, s.board.Width)
+	s.markAllChanged()
+}
+
+// Board returns the cells of the board
+func (s *Sparse) Board() [][]bool {
+	return s.board.Slice()
+}
+
+// ActiveTiles returns the number of tiles calculated on the last turn and the total number of tiles
+func (s *Sparse) ActiveTiles() (active, total int) {
+	return s.active, len(s.changed)
+}
+
+// Every tile has to be calculated until we know the last two turns
+func (s *Sparse) markAllChanged() {
+	for t := range s.changed {
+		s.changed[t] = true
+	}
+	s.fullTurns = 2
+}
+
+// Step calculates the next turn, splitting the rows of tiles between a number of threads
+func (s *Sparse) Step(threads int) {
+	active := s.activeTiles()
+	if s.fullTurns > 0 {
+		s.fullTurns--
+	}
+	s.active = 0
+	for _, a := range active {
+		if a {
+			s.active++
+		}
+	}
+
+	height, width := s.board.Height, s.board.Width
+	up := s.edgeRow(-1)
+	down := s.edgeRow(height)
+	counts := newRuleCounts(s.rule)
+	changed := make([]bool, len(s.changed))
+
+	if threads > s.tileRows {
+		threads = s.tileRows
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	var wg sync.WaitGroup
+	fragHeight := s.tileRows / threads
+	for i := 0; i < threads; i++ {
+		start := i * fragHeight
+		end := (i + 1) * fragHeight
+		if i == threads-1 {
+			end = s.tileRows
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			for tileRow := start; tileRow < end; tileRow++ {
+				for row := tileRow * sparseTileHeight; row < (tileRow+1)*sparseTileHeight && row < height; row++ {
+					above, below := up, down
+					if row > 0 {
+						above = s.board.Rows[row-1]
+					}
+					if row < height-1 {
+						below = s.board.Rows[row+1]
+					}
+
+					for col := 0; col < s.tileCols; col++ {
+						// Inactive tiles are left as they were on the last turn
+						tile := tileRow*s.tileCols + col
+						if !active[tile] {
+							continue
+						}
+						newWord := stepWord(above, s.board.Rows[row], below, col, width, counts, s.topology.WrapsColumns())
+						if newWord != s.next.Rows[row][col] {
+							changed[tile] = true
+						}
+						s.next.Rows[row][col] = newWord
+					}
+				}
+			}
+			wg.Done()
+		}(start, end)
+	}
+	wg.Wait()
+
+	s.board, s.next = s.next, s.board
+	s.changed = changed
+}
+
+// Find the tiles which are different to two turns ago or are next to one which is
+func (s *Sparse) activeTiles() []bool {
+	active := make([]bool, len(s.changed))
+	if s.fullTurns > 0 {
+		for t := range active {
+			active[t] = true
+		}
+		return active
+	}
+	for tileRow := 0; tileRow < s.tileRows; tileRow++ {
+		for tileCol := 0; tileCol < s.tileCols; tileCol++ {
+			if !s.changed[tileRow*s.tileCols+tileCol] {
+				continue
+			}
+			for r := tileRow - 1; r <= tileRow+1; r++ {
+				for c := tileCol - 1; c <= tileCol+1; c++ {
+					s.markActive(active, r, c)
+				}
+			}
+		}
+	}
+	return active
+}
+
+// Mark a tile as active, following the topology for tiles off the edge of the board
+func (s *Sparse) markActive(active []bool, tileRow, tileCol int) {
+	if tileCol < 0 || tileCol >= s.tileCols {
+		if !s.topology.WrapsColumns() {
+			return
+		}
+		tileCol = (tileCol + s.tileCols) % s.tileCols
+	}
+
+	if tileRow < 0 || tileRow >= s.tileRows {
+		if !s.topology.WrapsRows() {
+			return
+		}
+		// The row at the other end may be mirrored, so the whole row of tiles is marked
+		tileRow = (tileRow + s.tileRows) % s.tileRows
+		for c := 0; c < s.tileCols; c++ {
+			active[tileRow*s.tileCols+c] = true
+		}
+		return
+	}
+	active[tileRow*s.tileCols+tileCol] = true
+}
+
+// Get a row just above or below the board, following the topology
+// Rows off an edge which isn't joined are entirely dead
+func (s *Sparse) edgeRow(row int) []uint64 {
+	height, width := s.board.Height, s.board.Width
+	edge := make([]uint64, s.tileCols)
+	for col := 0; col < width; col++ {
+		if r, c, ok := s.topology.Neighbour(row, col, height, width); ok && s.board.Rows[r][c/wordSize]&(1<<uint(c%wordSize)) != 0 {
+			edge[col/wordSize] |= 1 << uint(col%wordSize)
+		}
+	}
+	return edge
+}
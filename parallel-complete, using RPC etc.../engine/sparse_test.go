@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// Calculate the next turn of a whole board one cell at a time, to check the sparse engine against
+func referenceTurn(board [][]bool, height, width int, rule stubs.Rule, topology stubs.Topology) [][]bool {
+	frag := DoTurnByCell(boardHalo(board, 0, height, height, width, topology), 1, rule, topology)
+	return frag.BitBoard.ToSlice()
+}
+
+// Make the halo of rows start to end of a board, like the server does
+// The rows above and below the board follow the topology
+func boardHalo(board [][]bool, start, end, height, width int, topology stubs.Topology) stubs.Halo {
+	cells := make([][]bool, 0, end-start+2)
+	for row := start - 1; row <= end; row++ {
+		if row >= 0 && row < height {
+			cells = append(cells, board[row])
+			continue
+		}
+		edge := make([]bool, width)
+		for col := range edge {
+			if r, c, ok := topology.Neighbour(row, col, height, width); ok {
+				edge[col] = board[r][c]
+			}
+		}
+		cells = append(cells, edge)
+	}
+	return stubs.Halo{
+		BitBoard: stubs.BitBoardFromSlice(cells, len(cells), width),
+		Offset:   1,
+		StartPtr: start,
+		EndPtr:   end,
+	}
+}
+
+func newBoard(height, width int) [][]bool {
+	board := make([][]bool, height)
+	for row := range board {
+		board[row] = make([]bool, width)
+	}
+	return board
+}
+
+func sameBoard(a, b [][]bool) bool {
+	for row := range a {
+		for col := range a[row] {
+			if a[row][col] != b[row][col] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Put a glider heading down and right on the board with its top left corner at (row, col), wrapping around the edges
+func placeGlider(board [][]bool, row, col int) {
+	height, width := len(board), len(board[0])
+	for _, cell := range [][2]int{{0, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}} {
+		board[(row+cell[0])%height][(col+cell[1])%width] = true
+	}
+}
+
+// TestSparseRandom compares the sparse engine with DoTurnByCell on random boards,
+// with sizes which don't fill the last tile and every topology, changing the board half way through
+func TestSparseRandom(t *testing.T) {
+	life, err := stubs.ParseRule("B3/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	highLife, err := stubs.ParseRule("B36/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := rand.New(rand.NewSource(1))
+	sizes := [][2]int{{1, 1}, {5, 7}, {16, 64}, {40, 100}, {33, 130}}
+	for _, rule := range []stubs.Rule{life, highLife} {
+		for _, topology := range []stubs.Topology{stubs.Torus, stubs.Plane, stubs.Cylinder, stubs.KleinBottle} {
+			for _, size := range sizes {
+				height, width := size[0], size[1]
+				name := rule.String() + "/" + topology.String() + "/" + strconv.Itoa(width) + "x" + strconv.Itoa(height)
+				t.Run(name, func(t *testing.T) {
+					board := newBoard(height, width)
+					for row := range board {
+						for col := range board[row] {
+							board[row][col] = random.Intn(3) == 0
+						}
+					}
+
+					sparse := NewSparse(board, height, width, rule, topology)
+					for turn := 1; turn <= 200; turn++ {
+						sparse.Step(3)
+						board = referenceTurn(board, height, width, rule, topology)
+						if !sameBoard(sparse.Board(), board) {
+							t.Fatalf("Boards differ on turn %d", turn)
+						}
+						active, total := sparse.ActiveTiles()
+						if active > total {
+							t.Fatalf("%d of %d tiles active on turn %d", active, total, turn)
+						}
+						// Changing the board part way through means every tile has to be calculated again
+						if turn == 100 {
+							for row := range board {
+								board[row][random.Intn(width)] = true
+							}
+							sparse.SetBoard(board)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestSparseGlider checks a glider keeps moving as it crosses the edges of tiles and wraps around the torus,
+// while the tiles it has left are skipped
+func TestSparseGlider(t *testing.T) {
+	life, err := stubs.ParseRule("B3/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		height, width int
+		row, col      int
+	}{
+		// Starts inside the first tile and crosses into the tiles to the right and below
+		{"tile edges", 96, 320, 10, 56},
+		// Starts in the bottom right corner, so it wraps onto the top and left of the board
+		{"torus wrap", 80, 250, 76, 246},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			board := newBoard(test.height, test.width)
+			placeGlider(board, test.row, test.col)
+			sparse := NewSparse(board, test.height, test.width, life, stubs.Torus)
+
+			for turn := 1; turn <= 120; turn++ {
+				sparse.Step(2)
+				board = referenceTurn(board, test.height, test.width, life, stubs.Torus)
+				if !sameBoard(sparse.Board(), board) {
+					t.Fatalf("Boards differ from DoTurnByCell on turn %d", turn)
+				}
+				// Every 4 turns the glider is back in shape, one cell down and right
+				if turn%4 == 0 {
+					expected := newBoard(test.height, test.width)
+					placeGlider(expected, test.row+turn/4, test.col+turn/4)
+					if !sameBoard(sparse.Board(), expected) {
+						t.Fatalf("Expected the glider to have moved %d cells on turn %d", turn/4, turn)
+					}
+				}
+			}
+
+			// Only the tiles around the glider are calculated once the board has settled
+			active, total := sparse.ActiveTiles()
+			if active >= total {
+				t.Errorf("Expected tiles away from the glider to be skipped, %d of %d were calculated", active, total)
+			}
+		})
+	}
+}
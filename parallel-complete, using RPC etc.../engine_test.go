@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestGolEngines runs the boards from TestGol with each engine on the server, TestGol itself uses the stateless engine.
+func TestGolEngines(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		engines := []stubs.Engine{stubs.Stateful, stubs.HashLife, stubs.Sparse}
+		tests := []gol.Params{
+			{ImageWidth: 16, ImageHeight: 16},
+			{ImageWidth: 64, ImageHeight: 64},
+			{ImageWidth: 512, ImageHeight: 512},
+		}
+		for _, engine := range engines {
+			for _, p := range tests {
+				p.Engine = engine
+				p.Threads = 4
+				for _, turns := range []int{0, 1, 100} {
+					p.Turns = turns
+					expectedAlive := readAliveCells(
+						"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+						p.ImageWidth,
+						p.ImageHeight,
+					)
+					testName := fmt.Sprintf("%v/%dx%dx%d", engine, p.ImageWidth, p.ImageHeight, p.Turns)
+					t.Run(testName, func(t *testing.T) {
+						events := make(chan gol.Event)
+						go gol.Run(p, events, nil)
+						var cells []util.Cell
+						for event := range events {
+							switch e := event.(type) {
+							case gol.FinalTurnComplete:
+								cells = e.Alive
+							}
+						}
+						assertEqualBoard(t, cells, expectedAlive, p)
+					})
+				}
+			}
+		}
+	}
+}
@@ -9,5 +9,6 @@ require (
 	github.com/veandco/go-sdl2 v0.4.4
 	golang.org/x/crypto v0.0.0-20201124201722-c8d3bf9c5392 // indirect
 	golang.org/x/perf v0.0.0-20220920022801-e8d778a60d07 // indirect
-	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1 // indirect
+	golang.org/x/sys v0.7.0
+	golang.org/x/term v0.0.0-20210422114643-f5beecf764ed
 )
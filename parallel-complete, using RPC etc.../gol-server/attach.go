@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/rpc"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file keeps track of the controller a game is sending its reports to
+// A game keeps running when its controller disconnects, and another controller can take over from the turn it has reached
+
+// controllerLink is the controller connected to a running game, if there is one
+// It is owned by the controllerLoop goroutine
+type controllerLink struct {
+	game          *session
+	client        *rpc.Client
+	visualUpdates bool
+	frameRate     int
+	// publisher sends the boards for visual updates, it is nil if the controller doesn't want them
+	publisher *visualPublisher
+}
+
+// Link the game to the controller which started it
+func newControllerLink(game *session, client *rpc.Client, visualUpdates bool, frameRate int) *controllerLink {
+	l := &controllerLink{game: game}
+	l.connect(client, visualUpdates, frameRate)
+	return l
+}
+
+func (l *controllerLink) connect(client *rpc.Client, visualUpdates bool, frameRate int) {
+	l.client = client
+	l.visualUpdates = visualUpdates
+	l.frameRate = frameRate
+	if visualUpdates {
+		l.publisher = newVisualPublisher(client, l.game.height, l.game.width, frameRate)
+	}
+}
+
+// Attach takes over from the last controller, which must have been detached
+// The new controller is sent the board on the current turn so it can catch up
+func (l *controllerLink) attach(a attachment, turn int, board func() [][]bool) {
+	l.connect(a.controller, a.visualUpdates, a.frameRate)
+	l.offer(turn, board)
+	println("Controller took over game", l.game.ID, "at turn", turn)
+}
+
+// Detach disconnects the controller after it has failed, leaving the game running without one
+// Nothing can be sent to the controller once it is detached, and it can be resumed by a new controller
+func (l *controllerLink) detach() {
+	if l.client == nil {
+		return
+	}
+	// Closing the client first fails a board still being sent, so a controller which has hung can't hold up the game
+	l.client.Close()
+	l.client = nil
+	if l.publisher != nil {
+		l.publisher.stop()
+		l.publisher = nil
+	}
+
+	sessionsMutex.Lock()
+	l.game.controller = nil
+	lastGameID = l.game.ID
+	sessionsMutex.Unlock()
+	println("Controller of game", l.game.ID, "disconnected, the game can be resumed")
+}
+
+// Connected returns whether there is a controller to send reports to
+func (l *controllerLink) connected() bool {
+	return l.client != nil
+}
+
+// EveryTurn returns whether the controller needs to see every turn
+func (l *controllerLink) everyTurn() bool {
+	return l.publisher != nil && l.frameRate == 0
+}
+
+// Call sends a report to the controller, doing nothing if there isn't one
+func (l *controllerLink) call(method string, args interface{}) error {
+	if l.client == nil {
+		return nil
+	}
+	return l.client.Call(method, args, &stubs.Empty{})
+}
+
+// Offer the board on a turn for visual updates, see visualPublisher.offer
+// The controller is detached if a board couldn't be sent to it
+func (l *controllerLink) offer(turn int, board func() [][]bool) {
+	if l.publisher == nil {
+		return
+	}
+	select {
+	case <-l.publisher.failed:
+		println("Error sending visual update to the controller")
+		l.detach()
+		return
+	default:
+	}
+	l.publisher.offer(turn, board)
+}
+
+// Flush sends the board for visual updates if the controller hasn't seen the current turn, e.g. when the game pauses
+func (l *controllerLink) flush(turn int, board func() [][]bool) {
+	if l.publisher != nil {
+		l.publisher.flush(turn, board, true)
+	}
+}
+
+// Finish sends the last board for visual updates, so the controller can be told the game has ended
+func (l *controllerLink) finish(turn int, board func() [][]bool) {
+	if l.publisher != nil {
+		l.publisher.finish(turn, board)
+	}
+}
+
+// Close stops sending boards and disconnects the controller when the game stops
+// The session still has the controller, so it must be cleared while holding sessionsMutex
+func (l *controllerLink) close() {
+	// The client is closed before the publisher is stopped, as in detach
+	if l.client != nil {
+		l.client.Close()
+		l.client = nil
+	}
+	if l.publisher != nil {
+		l.publisher.stop()
+		l.publisher = nil
+	}
+}
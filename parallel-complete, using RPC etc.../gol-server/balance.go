@@ -0,0 +1,116 @@
+package main
+
+import (
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains functions for splitting the board between workers depending on how fast they are
+
+// How much each new measurement of a worker changes its average throughput
+const rateSmoothing = 0.3
+
+// How far a strip boundary can drift, as a fraction of the average strip height,
+// before a stateful game is worth reloading to rebalance it
+const rebalanceTolerance = 0.05
+
+// Record how long a worker took to calculate a strip of the board
+func (w *worker) recordTurn(rows int, elapsed time.Duration) {
+	if rows <= 0 || elapsed <= 0 {
+		return
+	}
+	rate := float64(rows) / elapsed.Seconds()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.rowsPerSecond == 0 {
+		w.rowsPerSecond = rate
+	} else {
+		w.rowsPerSecond = rateSmoothing*rate + (1-rateSmoothing)*w.rowsPerSecond
+	}
+}
+
+// Get the average number of rows per second a worker calculates, or zero if it hasn't been measured
+func (w *worker) rate() float64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.rowsPerSecond
+}
+
+// Split the rows of a board between workers in proportion to how fast they are
+// Returns the first row of each strip followed by the height, so strip s is bounds[s] to bounds[s+1]
+// There must be no more workers than rows, as every strip gets at least one row
+func splitRows(height int, available []*worker) []int {
+	numStrips := len(available)
+	rates := make([]float64, numStrips)
+	total, measured := 0.0, 0
+	for s, worker := range available {
+		rates[s] = worker.rate()
+		if rates[s] > 0 {
+			total += rates[s]
+			measured++
+		}
+	}
+
+	// Workers we haven't measured yet are assumed to be average
+	guess := 1.0
+	if measured > 0 {
+		guess = total / float64(measured)
+	}
+	total = 0
+	for s := range rates {
+		if rates[s] == 0 {
+			rates[s] = guess
+		}
+		total += rates[s]
+	}
+
+	bounds := make([]int, numStrips+1)
+	cumulative := 0.0
+	for s := 0; s < numStrips; s++ {
+		bounds[s] = int(float64(height)*cumulative/total + 0.5)
+		cumulative += rates[s]
+	}
+	bounds[numStrips] = height
+
+	// Make sure every strip has at least one row
+	for s := 1; s < numStrips; s++ {
+		if bounds[s] <= bounds[s-1] {
+			bounds[s] = bounds[s-1] + 1
+		}
+	}
+	for s := numStrips - 1; s > 0; s-- {
+		if bounds[s] >= bounds[s+1] {
+			bounds[s] = bounds[s+1] - 1
+		}
+	}
+	return bounds
+}
+
+// Check if a new split has moved far enough from the old one to be worth using
+func splitChanged(previous, next []int, height int) bool {
+	if len(previous) != len(next) {
+		return true
+	}
+	tolerance := int(rebalanceTolerance * float64(height) / float64(len(next)-1))
+	for s := range previous {
+		diff := previous[s] - next[s]
+		if diff > tolerance || -diff > tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe a split for the status RPC
+func splitStatus(bounds []int, available []*worker) []stubs.StripStatus {
+	strips := make([]stubs.StripStatus, len(bounds)-1)
+	for s := range strips {
+		strips[s] = stubs.StripStatus{StartRow: bounds[s], EndRow: bounds[s+1]}
+		if s < len(available) {
+			strips[s].Address = available[s].Address
+		}
+	}
+	return strips
+}
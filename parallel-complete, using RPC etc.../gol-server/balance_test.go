@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// workersWithRates makes workers which have been measured at the given rows per second, zero hasn't been measured
+func workersWithRates(rates ...float64) []*worker {
+	workers := make([]*worker, len(rates))
+	for i, rate := range rates {
+		workers[i] = &worker{Address: "worker:" + strconv.Itoa(i), rowsPerSecond: rate}
+	}
+	return workers
+}
+
+func TestSplitRows(t *testing.T) {
+	tests := []struct {
+		height   int
+		rates    []float64
+		expected []int
+	}{
+		{30, []float64{0, 0, 0}, []int{0, 10, 20, 30}},
+		{31, []float64{0, 0, 0}, []int{0, 10, 21, 31}},
+		{40, []float64{100, 200, 100}, []int{0, 10, 30, 40}},
+		{40, []float64{5, 10, 5}, []int{0, 10, 30, 40}},
+		// Workers which haven't been measured are given the average rate of the others
+		{60, []float64{100, 0, 300}, []int{0, 10, 30, 60}},
+		{30, []float64{0, 50, 0}, []int{0, 10, 20, 30}},
+		// Every worker gets a row, however slow it is
+		{3, []float64{1, 1000, 1}, []int{0, 1, 2, 3}},
+		{10, []float64{1000, 1, 1}, []int{0, 8, 9, 10}},
+		{1, []float64{7}, []int{0, 1}},
+	}
+	for _, test := range tests {
+		bounds := splitRows(test.height, workersWithRates(test.rates...))
+		if !reflect.DeepEqual(bounds, test.expected) {
+			t.Errorf("%d rows at %v rows per second: expected %v, got %v", test.height, test.rates, test.expected, bounds)
+		}
+	}
+}
+
+func TestRecordTurn(t *testing.T) {
+	w := new(worker)
+	steps := []struct {
+		rows     int
+		elapsed  time.Duration
+		expected float64
+	}{
+		// The first measurement is used as it is, then each one moves the average by rateSmoothing
+		{100, time.Second, 100},
+		{200, time.Second, 130},
+		{50, 500 * time.Millisecond, 121},
+		// Nothing is learned from an empty strip or a turn which took no time
+		{0, time.Second, 121},
+		{100, 0, 121},
+	}
+	for i, step := range steps {
+		w.recordTurn(step.rows, step.elapsed)
+		if math.Abs(w.rate()-step.expected) > 1e-9 {
+			t.Errorf("Step %d: expected %v rows per second, got %v", i, step.expected, w.rate())
+		}
+	}
+}
+
+func TestSplitChanged(t *testing.T) {
+	// Two strips of 100 rows can move 2 rows before they are rebalanced
+	tests := []struct {
+		previous, next []int
+		expected       bool
+	}{
+		{[]int{0, 50, 100}, []int{0, 50, 100}, false},
+		{[]int{0, 50, 100}, []int{0, 52, 100}, false},
+		{[]int{0, 50, 100}, []int{0, 48, 100}, false},
+		{[]int{0, 50, 100}, []int{0, 53, 100}, true},
+		{[]int{0, 50, 100}, []int{0, 47, 100}, true},
+		{[]int{0, 50, 100}, []int{0, 33, 66, 100}, true},
+	}
+	for _, test := range tests {
+		if changed := splitChanged(test.previous, test.next, 100); changed != test.expected {
+			t.Errorf("%v to %v: expected %v, got %v", test.previous, test.next, test.expected, changed)
+		}
+	}
+}
+
+func TestSplitStatus(t *testing.T) {
+	strips := splitStatus([]int{0, 10, 30, 40}, workersWithRates(0, 0))
+	expected := []stubs.StripStatus{
+		{Address: "worker:0", StartRow: 0, EndRow: 10},
+		{Address: "worker:1", StartRow: 10, EndRow: 30},
+		// The server calculated the last strip itself
+		{Address: "", StartRow: 30, EndRow: 40},
+	}
+	if !reflect.DeepEqual(strips, expected) {
+		t.Errorf("Expected %v, got %v", expected, strips)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains functions for saving and loading checkpoints, so games survive a server restart
+
+// Checkpoint files are named after the game ID with this extension
+const checkpointExtension = ".gob"
+
+// checkpoint contains everything required to resume a game
+type checkpoint struct {
+	GameID   string
+	Board    *stubs.BitBoard
+	Turn     int
+	Height   int
+	Width    int
+	Rule     stubs.Rule
+	Topology stubs.Topology
+}
+
+// Write a checkpoint of a game to the checkpoint directory
+// The checkpoint is written to a temporary file first and then renamed over the old one,
+// so a crash while writing will never leave a half written checkpoint behind
+func saveCheckpoint(dir string, game *session) error {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(dir, "checkpoint-*.tmp")
+	if err != nil {
+		return err
+	}
+	// Make sure the temporary file is removed if anything goes wrong
+	defer os.Remove(file.Name())
+
+	err = gob.NewEncoder(file).Encode(checkpoint{
+		GameID:   game.ID,
+		Board:    stubs.BitBoardFromSlice(game.board, game.height, game.width),
+		Turn:     game.turn,
+		Height:   game.height,
+		Width:    game.width,
+		Rule:     game.rule,
+		Topology: game.topology,
+	})
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(file.Name(), filepath.Join(dir, game.ID+checkpointExtension))
+	if err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename itself is on disk
+	dirFile, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer dirFile.Close()
+	return dirFile.Sync()
+}
+
+// Read every checkpoint in the checkpoint directory
+// Checkpoints are returned oldest first, so the last one is the most recently saved game
+func loadCheckpoints(dir string) ([]*checkpoint, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	checkpoints := make([]*checkpoint, 0)
+	for _, info := range files {
+		// Skip any temporary files left over from a crash
+		if !strings.HasSuffix(info.Name(), checkpointExtension) {
+			continue
+		}
+		cp, err := loadCheckpoint(filepath.Join(dir, info.Name()))
+		if err != nil {
+			println("Error loading checkpoint", info.Name()+":", err.Error())
+			continue
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, nil
+}
+
+// Read a single checkpoint file
+func loadCheckpoint(path string) (*checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cp := new(checkpoint)
+	err = gob.NewDecoder(file).Decode(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Delete the checkpoint of a game which has ended, so it isn't loaded again when the server restarts
+// A game which was never checkpointed has nothing to delete
+func removeCheckpoint(dir string, id string) error {
+	err := os.Remove(filepath.Join(dir, id+checkpointExtension))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestCheckpoints saves some games and checks they load back the same, oldest first
+func TestCheckpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	highLife, _ := stubs.ParseRule("B36/S23")
+	games := []*session{
+		{ID: "first", board: randomBoard(16, 32, 1), turn: 10, height: 16, width: 32, rule: stubs.ConwayRule, topology: stubs.Torus},
+		{ID: "second", board: randomBoard(20, 10, 2), turn: 0, height: 20, width: 10, rule: highLife, topology: stubs.KleinBottle},
+		{ID: "third", board: randomBoard(1, 1, 3), turn: 5000, height: 1, width: 1, rule: stubs.ConwayRule, topology: stubs.Plane},
+	}
+	// Save the games newest first, and give them times so they load in the order above
+	start := time.Now().Add(-time.Hour)
+	for i := len(games) - 1; i >= 0; i-- {
+		err := saveCheckpoint(dir, games[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTime := start.Add(time.Duration(i) * time.Minute)
+		err = os.Chtimes(filepath.Join(dir, games[i].ID+checkpointExtension), modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Saving a game again replaces its checkpoint
+	games[0].turn = 11
+	games[0].board = randomBoard(16, 32, 4)
+	err = saveCheckpoint(dir, games[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	games = append(games[1:], games[0])
+
+	// Temporary files from a crash and corrupt checkpoints are skipped
+	err = ioutil.WriteFile(filepath.Join(dir, "checkpoint-123.tmp"), []byte("half written"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "corrupt"+checkpointExtension), []byte("not a checkpoint"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoints, err := loadCheckpoints(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != len(games) {
+		t.Fatalf("Expected %d checkpoints, got %d", len(games), len(checkpoints))
+	}
+	for i, cp := range checkpoints {
+		game := games[i]
+		if cp.GameID != game.ID || cp.Turn != game.turn || cp.Height != game.height || cp.Width != game.width ||
+			cp.Rule != game.rule || cp.Topology != game.topology {
+			t.Errorf("Checkpoint %d: expected game %s on turn %d, got %+v", i, game.ID, game.turn, cp)
+			continue
+		}
+		if !sameBoard(cp.Board.ToSlice(), game.board) {
+			t.Errorf("Checkpoint %d: the board of game %s is wrong", i, game.ID)
+		}
+	}
+
+	// Only the checkpoints and the files made by the test are left
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(games)+2 {
+		for _, file := range files {
+			t.Log(file.Name())
+		}
+		t.Errorf("Expected %d files in the checkpoint directory, got %d", len(games)+2, len(files))
+	}
+}
+
+// TestLoadCheckpointsMissing checks a checkpoint directory which hasn't been made yet has no checkpoints
+func TestLoadCheckpointsMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	checkpoints, err := loadCheckpoints(filepath.Join(dir, "missing"))
+	if err != nil || len(checkpoints) != 0 {
+		t.Errorf("Expected no checkpoints and no error, got %d and %v", len(checkpoints), err)
+	}
+}
+
+// TestRemoveCheckpoint checks an ended game's checkpoint is deleted and isn't loaded again
+func TestRemoveCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	games := []*session{
+		{ID: "ended", board: randomBoard(8, 8, 1), turn: 100, height: 8, width: 8, rule: stubs.ConwayRule, topology: stubs.Torus},
+		{ID: "running", board: randomBoard(8, 8, 2), turn: 50, height: 8, width: 8, rule: stubs.ConwayRule, topology: stubs.Torus},
+	}
+	for _, game := range games {
+		err := saveCheckpoint(dir, game)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = removeCheckpoint(dir, "ended")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A game which was never checkpointed has nothing to remove
+	err = removeCheckpoint(dir, "never saved")
+	if err != nil {
+		t.Errorf("Expected no error removing a missing checkpoint, got %v", err)
+	}
+
+	checkpoints, err := loadCheckpoints(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 1 || checkpoints[0].GameID != "running" {
+		t.Errorf("Expected only the checkpoint of the running game, got %d checkpoints", len(checkpoints))
+	}
+}
+
+// Wait for a game's loop to stop, failing the test if it takes too long
+func waitForStop(t *testing.T, game *session) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		sessionsMutex.Lock()
+		running := game.running
+		sessionsMutex.Unlock()
+		if !running {
+			return
+		}
+	}
+	t.Fatal("Timed out waiting for the game to stop")
+}
+
+// Serve a FakeController over TCP, as StartGame dials the controller's address
+func listenFakeController(t *testing.T) (string, func()) {
+	server := rpc.NewServer()
+	err := server.RegisterName("Controller", new(FakeController))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Accept(listener)
+	return listener.Addr().String(), func() { listener.Close() }
+}
+
+// TestQuitKeepsCheckpoint quits a game with 'q', loads it back from its checkpoint as a restarted server would,
+// and checks a new controller can resume it to its last turn, after which the checkpoint is removed
+func TestQuitKeepsCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	previousDir, previousInterval := checkpointDir, checkpointInterval
+	checkpointDir, checkpointInterval = dir, 0
+	defer func() { checkpointDir, checkpointInterval = previousDir, previousInterval }()
+
+	_, cleanupWorkers := connectFakeWorkers(t, 0, &FakeWorker{})
+	defer cleanupWorkers()
+
+	const height, width = 16, 16
+	client, cleanupController := connectFakeController(t, new(FakeController))
+	defer cleanupController()
+	game := newSession(stubs.StartGameRequest{Board: stubs.BitBoardFromSlice(randomBoard(height, width, 1), height, width), Height: height, Width: width})
+	game.controller = client
+	game.maxTurns = 1 << 30
+	game.observers = newObserverSet()
+	game.running = true
+	sessionsMutex.Lock()
+	sessions[game.ID] = game
+	sessionsMutex.Unlock()
+	defer func() {
+		sessionsMutex.Lock()
+		delete(sessions, game.ID)
+		sessionsMutex.Unlock()
+	}()
+
+	go controllerLoop(game)
+	time.Sleep(50 * time.Millisecond)
+	game.keypresses <- 'q'
+	waitForStop(t, game)
+
+	// The server restarts, loading the game from its checkpoint
+	checkpoints, err := loadCheckpoints(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 1 || checkpoints[0].GameID != game.ID || checkpoints[0].Turn != game.turn {
+		t.Fatalf("Expected a checkpoint of game %s on turn %d, got %d checkpoints", game.ID, game.turn, len(checkpoints))
+	}
+	if !sameBoard(checkpoints[0].Board.ToSlice(), game.board) {
+		t.Fatal("The checkpoint has the wrong board")
+	}
+	restored := sessionFromCheckpoint(checkpoints[0])
+	sessionsMutex.Lock()
+	sessions[game.ID] = restored
+	sessionsMutex.Unlock()
+
+	// A new controller resumes the game for a few more turns
+	address, cleanupListener := listenFakeController(t)
+	defer cleanupListener()
+	lastTurn := restored.turn + 5
+	res := new(stubs.StartGameResponse)
+	err = new(Server).StartGame(stubs.StartGameRequest{ControllerAddress: address, GameID: game.ID, MaxTurns: lastTurn, Threads: 1}, res)
+	if err != nil || !res.Success {
+		t.Fatalf("Expected to resume the game, got %v and %q", err, res.Message)
+	}
+	waitForStop(t, restored)
+
+	expected := checkpoints[0].Board.ToSlice()
+	for turn := checkpoints[0].Turn; turn < lastTurn; turn++ {
+		expected = expectedTurn(expected, height, width, stubs.ConwayRule, stubs.Torus)
+	}
+	if restored.turn != lastTurn || !sameBoard(restored.board, expected) {
+		t.Errorf("Expected the resumed game to reach turn %d with the right board, it reached turn %d", lastTurn, restored.turn)
+	}
+	// The game has ended, so it isn't loaded again after another restart
+	checkpoints, err = loadCheckpoints(dir)
+	if err != nil || len(checkpoints) != 0 {
+		t.Errorf("Expected the checkpoint to be removed once the game ended, got %d checkpoints and %v", len(checkpoints), err)
+	}
+}
+
+// TestOrphanTimeout leaves a game running without a controller, and checks it pauses and is checkpointed
+// once the orphan timeout has passed, then that a controller can take it over
+func TestOrphanTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	previousDir, previousInterval, previousTimeout := checkpointDir, checkpointInterval, orphanTimeout
+	checkpointDir, checkpointInterval, orphanTimeout = dir, 0, 100*time.Millisecond
+	defer func() { checkpointDir, checkpointInterval, orphanTimeout = previousDir, previousInterval, previousTimeout }()
+
+	_, cleanupWorkers := connectFakeWorkers(t, 0, &FakeWorker{})
+	defer cleanupWorkers()
+
+	// The game has no controller, and keeps running by default
+	const height, width = 16, 16
+	game := newSession(stubs.StartGameRequest{Board: stubs.BitBoardFromSlice(randomBoard(height, width, 1), height, width), Height: height, Width: width})
+	game.maxTurns = 1 << 30
+	game.onDisconnect = stubs.KeepRunning
+	game.observers = newObserverSet()
+	game.running = true
+	sessionsMutex.Lock()
+	sessions[game.ID] = game
+	sessionsMutex.Unlock()
+	defer func() {
+		sessionsMutex.Lock()
+		delete(sessions, game.ID)
+		sessionsMutex.Unlock()
+	}()
+	go controllerLoop(game)
+
+	// Once paused, the checkpoint stays on the same turn
+	var checkpoints []*checkpoint
+	for start := time.Now(); len(checkpoints) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("Timed out waiting for the game to be checkpointed")
+		}
+		checkpoints, err = loadCheckpoints(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	later, err := loadCheckpoints(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(later) != 1 || later[0].Turn != checkpoints[0].Turn {
+		t.Fatalf("Expected the game to stay paused on turn %d", checkpoints[0].Turn)
+	}
+
+	// A controller takes over and finishes the game a few turns later
+	address, cleanupListener := listenFakeController(t)
+	defer cleanupListener()
+	lastTurn := checkpoints[0].Turn + 5
+	res := new(stubs.StartGameResponse)
+	err = new(Server).StartGame(stubs.StartGameRequest{ControllerAddress: address, GameID: game.ID, MaxTurns: lastTurn, Threads: 1}, res)
+	if err != nil || !res.Success {
+		t.Fatalf("Expected to take over the game, got %v and %q", err, res.Message)
+	}
+	waitForStop(t, game)
+	if game.turn != lastTurn {
+		t.Errorf("Expected the game to reach turn %d, it reached turn %d", lastTurn, game.turn)
+	}
+}
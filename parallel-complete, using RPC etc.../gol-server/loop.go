@@ -1,228 +1,424 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
-	"sync"
+	"net/rpc"
 	"time"
 
+	"uk.ac.bris.cs/gameoflife/engine"
 	"uk.ac.bris.cs/gameoflife/stubs"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // This file contains game loop functions. RPC and others are in server.go
 
+// errDeadline is returned when a worker takes longer than callDeadline to return a fragment
+var errDeadline = errors.New("worker missed the deadline")
+
+// fragmentResult is the outcome of asking a worker to process one fragment of the board
+type fragmentResult struct {
+	index  int
+	worker *worker
+	frag   stubs.Fragment
+	err    error
+}
+
 // Send a portion of the board to a worker to process the turn for
-// When we get a fragment back, send it down the frag channel
-func doWorker(halo stubs.Halo, newBoard [][]bool, threads int, worker *worker, failChan chan<- bool, fragChan chan<- stubs.Fragment) {
+// When we get a fragment back (or the worker fails), send the result down the results channel
+func doWorker(index int, halo stubs.Halo, threads int, rule stubs.Rule, topology stubs.Topology, worker *worker, results chan<- fragmentResult) {
 	response := stubs.DoTurnResponse{}
+	start := time.Now()
+	rows := halo.EndPtr - halo.StartPtr
+
+	// Send the halo to the client, waiting no longer than the deadline for the result
+	call := worker.Client.Go(stubs.WorkerDoTurn,
+		stubs.DoTurnRequest{Halo: halo, Threads: threads, Rule: rule, Topology: topology}, &response, make(chan *rpc.Call, 1))
+
+	// A nil channel is never ready, so there is no deadline when it is disabled
+	var deadline <-chan time.Time
+	if callDeadline > 0 {
+		timer := time.NewTimer(callDeadline)
+		defer timer.Stop()
+		deadline = timer.C
+	}
 
-	// Send the halo to the client, get the result
-	err := worker.Client.Call(stubs.WorkerDoTurn,
-		stubs.DoTurnRequest{Halo: halo, Threads: threads}, &response)
-	if err != nil {
-		println("Error getting fragment:", err.Error())
-		disconnectWorker(worker)
-		failChan <- true
-		return
+	select {
+	case <-call.Done:
+		if call.Error == nil {
+			worker.recordTurn(rows, time.Since(start))
+		}
+		results <- fragmentResult{index: index, worker: worker, frag: response.Frag, err: call.Error}
+	case <-deadline:
+		// Count the missed deadline so a slow worker is given fewer rows
+		worker.recordTurn(rows, callDeadline)
+		results <- fragmentResult{index: index, worker: worker, err: errDeadline}
 	}
-	fragChan <- response.Frag
 }
 
 // Create a "halo" of cells containing only the cells required to calculat the next turn
-// Take the whole board and return a halo which can be passed to a worker
-func makeHalo(worker int, fragHeight int, numWorkers int, height, width int, board [][]bool) stubs.Halo {
+// Take the whole board and return a halo for rows start to end which can be passed to a worker
+// The rows above and below the fragment are always included, following the board topology
+func makeHalo(start, end int, height, width int, board [][]bool, topology stubs.Topology) stubs.Halo {
 	cells := make([][]bool, 0)
 
-	start := worker * fragHeight
-	end := (worker + 1) * fragHeight
-	if worker == numWorkers-1 {
-		end = height
-	}
-
-	downPtr := end % height // "max row + 1"
-	upPtr := (start - 1)    // "min row - 1"
-	if upPtr == -1 {
-		upPtr = height - 1
-	}
-	workPtr := 0
-
-	if upPtr != end-1 {
-		cells = append(cells, board[upPtr])
-		workPtr = 1
-	}
+	cells = append(cells, edgeRow(start-1, height, width, board, topology)) // "min row - 1"
 	for row := start; row < end; row++ {
 		cells = append(cells, board[row])
 	}
-	if downPtr != start {
-		cells = append(cells, board[downPtr])
-	}
+	cells = append(cells, edgeRow(end, height, width, board, topology)) // "max row + 1"
+
 	return stubs.Halo{
-		BitBoard: stubs.BitBoardFromSlice(cells, len(cells), width), 
-		Offset:   workPtr,
+		BitBoard: stubs.BitBoardFromSlice(cells, len(cells), width),
+		Offset:   1,
 		StartPtr: start,
 		EndPtr:   end,
 	}
 }
 
-// Update board is called every time we want to process a turn
-// This will partition the board up and send each fragment to a worker
-// Workers will copy the new turn onto the newBoard slice
-// Returns true if there have been no errors (and the whole board has been set)
-func updateBoard(board [][]bool, newBoard [][]bool, height, width int, threads int) bool {
-	// Create a WaitGroup so we only return when all workers have finished
-	var wg sync.WaitGroup
-	failChan := make(chan bool)
-	workersMutex.Lock()
+// Get a row of the board which may be just above or below the board
+// Rows off an edge which isn't joined are entirely dead
+func edgeRow(row int, height, width int, board [][]bool, topology stubs.Topology) []bool {
+	if row >= 0 && row < height {
+		return board[row]
+	}
 
-	// Calculate the number of rows each worker thread should use
-	numWorkers := len(workers)
+	edge := make([]bool, width)
+	for col := 0; col < width; col++ {
+		if r, c, ok := topology.Neighbour(row, col, height, width); ok {
+			edge[col] = board[r][c]
+		}
+	}
+	return edge
+}
 
-	if numWorkers == 0 {
-		return false
+// Update board is called every time we want to process a turn
+// This will partition the board up and send each fragment to a worker,
+// giving faster workers more rows so they all finish at about the same time
+// The new turn is copied onto the newBoard slice as fragments come back
+// If a worker fails only its fragment is sent to another worker,
+// and if there are no workers left the fragment is calculated here instead
+// The split of the board that was used is returned
+func updateBoard(board [][]bool, newBoard [][]bool, height, width int, threads int, rule stubs.Rule, topology stubs.Topology) []stubs.StripStatus {
+	available := availableWorkers(height)
+
+	// Calculate the rows each worker should use
+	numFragments := len(available)
+	bounds := []int{0, height}
+	if numFragments == 0 {
+		numFragments = 1
+	} else {
+		bounds = splitRows(height, available)
 	}
-	fragHeight := height / numWorkers
 
-	wg.Add(numWorkers)
-	fragChan := make(chan stubs.Fragment, numWorkers)
+	halos := make([]stubs.Halo, numFragments)
+	results := make(chan fragmentResult, numFragments)
+	for f := 0; f < numFragments; f++ {
+		halos[f] = makeHalo(bounds[f], bounds[f+1], height, width, board, topology)
+	}
+	for w, worker := range available {
+		go doWorker(w, halos[w], threads, rule, topology, worker, results)
+	}
 
-	for w := 0; w < numWorkers; w++ {
-		thisWorker := workers[w]
-		go func(workerIdx int, worker *worker) {
+	// Workers which have failed this turn, so they won't be given any more fragments
+	failed := make(map[*worker]bool)
+	nextWorker := 0
 
-			halo := makeHalo(workerIdx, fragHeight, numWorkers, height, width, board)
-			// Send the fragment to the worker
-			doWorker(halo, newBoard, threads, worker, failChan, fragChan)
-		}(w, thisWorker)
+	remaining := numFragments
+	if len(available) == 0 {
+		println("No workers available, calculating the turn locally")
+		copyFragment(newBoard, engine.DoTurn(halos[0], threads, rule, topology))
+		remaining = 0
 	}
 
-	// We can release workers now
-	workersMutex.Unlock()
+	for remaining > 0 {
+		result := <-results
+		if result.err == nil {
+			copyFragment(newBoard, result.frag)
+			remaining--
+			continue
+		}
 
-	i := 0
-	fail := false
-	for i < numWorkers {
-		select {
-		case fail = <-failChan:
-			i++
-		case frag := <-fragChan:
+		println("Error getting fragment from", result.worker.Address+":", result.err.Error())
+		failed[result.worker] = true
+		// A slow worker might recover, but one which returned an error has gone
+		if result.err != errDeadline {
+			disconnectWorker(result.worker)
+		}
 
-			respCells := frag.BitBoard.ToSlice()
-			for row := frag.StartRow; row < frag.EndRow; row++ {
-				copy(newBoard[row], respCells[row-frag.StartRow])
+		// Reissue just this fragment to the next surviving worker
+		var survivor *worker
+		for tries := 0; tries < len(available) && survivor == nil; tries++ {
+			candidate := available[nextWorker%len(available)]
+			nextWorker++
+			if !failed[candidate] {
+				survivor = candidate
 			}
-			i++
 		}
-	}
 
-	// Check that there have been no fails
-	if fail {
-		// One or more of the workers have hit a problem
-		return false
+		if survivor != nil {
+			println("Reassigning fragment", result.index, "to", survivor.Address)
+			go doWorker(result.index, halos[result.index], threads, rule, topology, survivor, results)
+		} else {
+			println("No workers left, calculating fragment", result.index, "locally")
+			copyFragment(newBoard, engine.DoTurn(halos[result.index], threads, rule, topology))
+			remaining--
+		}
 	}
+	return splitStatus(bounds, available)
+}
 
-	return true
+// Copy the cells from a fragment into its rows of the board
+func copyFragment(board [][]bool, frag stubs.Fragment) {
+	respCells := frag.BitBoard.ToSlice()
+	for row := frag.StartRow; row < frag.EndRow; row++ {
+		copy(board[row], respCells[row-frag.StartRow])
+	}
 }
 
-// This function contains the game loop and sends messages to the controller
-// It will return when the final turn is completed or there is an error
-// When it returns, the controller is disconnected and the server can accept new connections
-func controllerLoop(board [][]bool, startTurn, height, width, maxTurns, threads int, visualUpdates bool) {
+// This function contains the game loop for a session and sends messages to its controller
+// It will return when the final turn is completed, the controller quits or there is an error
+// If the controller disconnects without quitting, the game carries on (or pauses) until a new controller takes over
+// A game left running without a controller for orphanTimeout pauses too, so it doesn't hold the workers forever
+// When it returns, the controller is disconnected and the game can be resumed by a new controller
+func controllerLoop(game *session) {
+
+	turn := game.turn
+	height, width := game.height, game.width
+	maxTurns, onDisconnect := game.maxTurns, game.onDisconnect
+
+	runner := newRunner(game)
+	println("Using the", game.engine.String(), "engine")
+	// The turn is known to Status and Step before the first turn is calculated
+	game.setSplit(turn, runner.Split())
+	game.ages = nil
+	if game.trackAges {
+		game.ages = engine.NewAges(runner.Board())
+	}
+
+	// Boards are sent from another goroutine at the controller's frame rate, see visual.go
+	controller := newControllerLink(game, game.controller, game.visualUpdates, game.frameRate)
+
+	// A game resumed after stopping starts running at full speed, see pause.go
+	game.paused, game.stopAt, game.turnsPerSecond = false, -1, 0
+	var lastTurnAt time.Time
+	// When the game was left without a controller, zero while it has one
+	var orphanedAt time.Time
+
+	// A game which ended on its last turn or with 'k' is not checkpointed, and 'k' closes the server once the game has stopped
+	// A game quit with 'q' is kept, so a new controller can take over even after a restart
+	ended, closeServer := false, false
 
 	defer func() {
+		// Observers are told the game has stopped, unless they were already sent the final turn
+		game.observers.end(runner.Turn(), runner.Board, stubs.ControllerGameStateChange,
+			stubs.StateChangeReport{Previous: stubs.Executing, New: stubs.Quitting, CompletedTurns: runner.Turn()})
+		controller.close()
+		game.board = runner.Board()
+		runner.Close()
+
+		// Keep a final checkpoint so the game can be resumed after we stop
+		// A game which has ended is removed instead, so it isn't loaded again after a restart
+		if checkpointDir != "" && ended {
+			err := removeCheckpoint(checkpointDir, game.ID)
+			if err != nil {
+				println("Error removing checkpoint:", err.Error())
+			}
+		} else if checkpointDir != "" {
+			err := saveCheckpoint(checkpointDir, game)
+			if err != nil {
+				println("Error saving checkpoint:", err.Error())
+			}
+		}
+
+		sessionsMutex.Lock()
+		game.controller = nil
+		game.running = false
+		lastGameID = game.ID
+		// A controller may have taken over just as the game stopped, so resume the game for it
+		select {
+		case a := <-game.attachments:
+			resumeAttached(game, a)
+		default:
+		}
+		sessionsMutex.Unlock()
+		println("Disconnected Controller from game", game.ID)
 
-		controllerMutex.Lock()
-		controller.Close()
-		controller = nil
-		controllerMutex.Unlock()
-		println("Disconnected Controller")
+		// Closing our listener will close our RPC server, so this is done after the checkpoint is removed
+		if closeServer {
+			listener.Close()
+		}
 	}()
 
 	ticker := time.NewTicker(2 * time.Second)
 
-	turn := startTurn
-
-	newBoard := make([][]bool, height)
-	for row := 0; row < height; row++ {
-		newBoard[row] = make([]bool, width)
+	// A nil channel is never ready, so checkpointing is skipped when disabled
+	var checkpointTick <-chan time.Time
+	if checkpointDir != "" && checkpointInterval > 0 {
+		checkpointTicker := time.NewTicker(checkpointInterval)
+		defer checkpointTicker.Stop()
+		checkpointTick = checkpointTicker.C
 	}
+
 	println("Max turns: ", maxTurns)
 
+	controller.offer(turn, runner.Board)
 
-	if visualUpdates {
-		controller.Call(stubs.ControllerTurnComplete,
-			stubs.BoardStateReport{CompletedTurns: turn, Board: stubs.BitBoardFromSlice(board, height, width)}, &stubs.Empty{})
+	// A controller taking over brings its own settings, except for the engine
+	takeOver := func(a attachment) {
+		maxTurns, onDisconnect = a.maxTurns, a.onDisconnect
+		if !a.trackAges {
+			game.ages = nil
+		} else if game.ages == nil {
+			game.ages = engine.NewAges(runner.Board())
+		}
+		controller.attach(a, turn, runner.Board)
+		if game.paused {
+			controller.call(stubs.ControllerGameStateChange,
+				stubs.StateChangeReport{Previous: stubs.Executing, New: stubs.Paused, CompletedTurns: turn})
+		}
 	}
 
-
 	for turn < maxTurns {
+		if controller.connected() {
+			orphanedAt = time.Time{}
+		} else if orphanedAt.IsZero() {
+			orphanedAt = time.Now()
+		}
+		orphaned := !orphanedAt.IsZero() && orphanTimeout > 0 && time.Since(orphanedAt) >= orphanTimeout
+
+		// Without a controller, a game which pauses on disconnect (or has run for too long) waits for one to take over
+		if !controller.connected() && (onDisconnect == stubs.PauseGame || orphaned) {
+			if orphaned && onDisconnect != stubs.PauseGame {
+				println("Game", game.ID, "has had no controller for", orphanTimeout.String())
+			}
+			println("Game", game.ID, "paused at turn", turn, "until a controller resumes it")
+			if checkpointDir != "" {
+				game.board = runner.Board()
+				err := saveCheckpoint(checkpointDir, game)
+				if err != nil {
+					println("Error saving checkpoint:", err.Error())
+				}
+			}
+			takeOver(<-game.attachments)
+			continue
+		}
+
 		select {
 
-		case key := <-keypresses:
+		case a := <-game.attachments:
+			takeOver(a)
+
+		case key := <-game.keypresses:
+			// Keys can be left over from a controller which has disconnected
+			if !controller.connected() {
+				continue
+			}
 			println("Received keypress: ", key)
-			quit := handleKeypress(key, turn, board, height, width)
+			// The last board must reach the controller before it hears the game has ended
+			if key == 'q' || key == 'k' {
+				controller.finish(runner.Turn(), runner.Board)
+			}
+			quit, shutdown := handleKeypress(game, controller, key, runner)
 			if quit {
+				ended, closeServer = key == 'k', shutdown
 				return
 			}
 
 		case <-ticker.C:
 			println("Telling controller number of cells alive")
+			report := stubs.AliveCellsReport{CompletedTurns: turn, NumAlive: len(util.GetAliveCells(runner.Board()))}
+			if tiles, ok := runner.(tileReporter); ok {
+				report.ActiveTiles, report.TotalTiles = tiles.ActiveTiles()
+			}
+			game.observers.send(stubs.ControllerReportAliveCells, report)
 			// Make the RPC call
-			err := controller.Call(stubs.ControllerReportAliveCells,
-				stubs.AliveCellsReport{CompletedTurns: turn, NumAlive: len(util.GetAliveCells(board))}, &stubs.Empty{})
+			err := controller.call(stubs.ControllerReportAliveCells, report)
 
 			if err != nil {
 				fmt.Println("Error sending num alive ", err)
-				return
+				controller.detach()
 			}
 
-		default:
-			// Get the next board state (this will send calls to workers)
-			success := updateBoard(board, newBoard, height, width, threads)
-
-			if success {
-
-				for row := 0; row < height; row++ {
-					copy(board[row], newBoard[row])
-				}
-				if visualUpdates {
+		case command := <-game.commands:
+			// Commands can be left over from a controller which has disconnected, like keys
+			if controller.connected() {
+				handleCommand(game, controller, command, turn, runner.Board)
+			}
 
-					controller.Call(stubs.ControllerTurnComplete,
-						stubs.BoardStateReport{CompletedTurns: turn, Board: stubs.BitBoardFromSlice(board, height, width)}, &stubs.Empty{})
-				}
-				turn++
+		case <-checkpointTick:
+			println("Saving checkpoint at turn", turn)
+			game.board = runner.Board()
+			err := saveCheckpoint(checkpointDir, game)
+			if err != nil {
+				println("Error saving checkpoint:", err.Error())
+			}
 
+		case <-nextTurn(game, lastTurnAt):
+			// Get the next board state (this will send calls to workers)
+			// Every turn is needed for ages, unlimited visual updates and limited speeds, otherwise the runner can skip ahead
+			limit := maxTurns
+			if game.stopAt >= 0 && game.stopAt < limit {
+				limit = game.stopAt
+			}
+			if game.ages != nil || controller.everyTurn() || game.turnsPerSecond > 0 {
+				limit = turn + 1
+			}
+			runner.Step(limit)
+			game.setSplit(runner.Turn(), runner.Split())
+			turn = runner.Turn()
+			game.turn = turn
+			if game.ages != nil {
+				game.ages.Update(runner.Board())
+			}
 
-				lastBoardState = board
-				lastTurn = turn
-			} else {
-				if len(workers) == 0 {
-					return
-				}
+			lastTurnAt = time.Now()
 
-				println("Encountered a problem handling turn", turn)
-				println("Retrying this turn")
+			controller.offer(turn, runner.Board)
+			game.observers.offer(turn, runner.Board)
+			// Stepping to the last turn finishes the game rather than pausing it
+			if turn < maxTurns {
+				checkStep(game, controller, turn, runner.Board)
 			}
 		}
 
 	}
 
 	println("All turns done, send final turn complete")
+	controller.finish(turn, runner.Board)
 
 
-	err := controller.Call(stubs.ControllerFinalTurnComplete,
-		stubs.BoardStateReport{
-			CompletedTurns: maxTurns,
-			Board:          stubs.BitBoardFromSlice(board, height, width),
-		},
-		&stubs.Empty{})
+	final := stubs.BoardStateReport{
+		CompletedTurns: maxTurns,
+		Board:          stubs.BitBoardFromSlice(runner.Board(), height, width),
+		Ages:           game.agesReport(),
+	}
+	game.observers.end(turn, runner.Board, stubs.ControllerFinalTurnComplete, final)
+	err := controller.call(stubs.ControllerFinalTurnComplete, final)
 	if err != nil {
 		fmt.Println("Error sending final turn complete ", err)
 	}
 	// End the game
+	ended = true
 	return
 }
 
+// Start the game loop again for a controller which took over as the game stopped
+// sessionsMutex must be held
+func resumeAttached(game *session, a attachment) {
+	println("Resuming game", game.ID, "for the controller which took over")
+	game.controller = a.controller
+	game.maxTurns = a.maxTurns
+	game.visualUpdates = a.visualUpdates
+	game.frameRate = a.frameRate
+	game.trackAges = a.trackAges
+	game.onDisconnect = a.onDisconnect
+	game.observers = newObserverSet()
+	game.running = true
+	go controllerLoop(game)
+}
+
 
 
 func randomiseBoard(board [][]bool, height, width int) {
@@ -241,6 +437,20 @@ func randomiseBoard(board [][]bool, height, width int) {
 	}
 }
 
+// Take a copy of the workers so they can connect and disconnect while we use them
+// Every strip must have at least one row, so there are never more workers than rows
+func availableWorkers(height int) []*worker {
+	workersMutex.Lock()
+	available := make([]*worker, len(workers))
+	copy(available, workers)
+	workersMutex.Unlock()
+
+	if len(available) > height {
+		available = available[:height]
+	}
+	return available
+}
+
 // Cleanly disconnect a worker and remove it from the workers slice
 func disconnectWorker(worker *worker) {
 	// Lock the workers slice to get exclusive access
@@ -262,63 +472,69 @@ func disconnectWorker(worker *worker) {
 	println("We aren't connected to worker", worker.Address)
 }
 
-// Handle keypress sent from the client
-func handleKeypress(key rune, turn int, board [][]bool, height, width int) bool {
+// Handle keypress sent from the client of a game
+// Returns whether the game has stopped, and whether the server should close once it has
+func handleKeypress(game *session, link *controllerLink, key rune, runner turnRunner) (bool, bool) {
+	controller := link.client
+	turn := runner.Turn()
+	height, width := game.height, game.width
 	switch key {
 	case 'q':
 	
 		controller.Call(stubs.ControllerGameStateChange,
 			stubs.StateChangeReport{Previous: stubs.Executing, New: stubs.Quitting, CompletedTurns: turn}, &stubs.Empty{})
 		println("Closing controller")
-		return true
+		return true, false
 	case 'p':
-		// Pause: pause execution and wait for another P
-		println("Pausing execution")
-
-		controller.Call(stubs.ControllerGameStateChange,
-			stubs.StateChangeReport{Previous: stubs.Executing, New: stubs.Paused, CompletedTurns: turn}, &stubs.Empty{})
-		// Wait for another P
-		for <-keypresses != 'p' {
-		}
-		// Tell the controller we're resuming
-		controller.Call(stubs.ControllerGameStateChange,
-			stubs.StateChangeReport{Previous: stubs.Paused, New: stubs.Executing, CompletedTurns: turn}, &stubs.Empty{})
-		println("Resuming execution")
+		// Pause, or resume a paused game, which carries on reporting to the controller while it is paused
+		// A game being stepped is paused where it is, and runs on without stopping when it is resumed
+		game.stopAt = -1
+		setPaused(game, link, !game.paused, turn, runner.Board)
 	case 's':
 
 		println("Telling controller to save board")
 
 		controller.Call(stubs.ControllerSaveBoard,
-			stubs.BoardStateReport{CompletedTurns: turn, Board: stubs.BitBoardFromSlice(board, height, width)}, &stubs.Empty{})
+			stubs.BoardStateReport{CompletedTurns: turn, Board: stubs.BitBoardFromSlice(runner.Board(), height, width), Ages: game.agesReport()}, &stubs.Empty{})
 	case 'k':
-
-
-		println("Controller wants to close everything")
-
-	
-		for w := 0; w < len(workers); w++ {
-			println("Disconnecting worker", w)
-		
-			workers[w].Client.Call(stubs.WorkerShutdown, stubs.Empty{}, &stubs.Empty{})
-			workers[w].Client.Close()
+		// The workers and the server are shared by every game, so they are only shut down if no other game is running
+		// Otherwise only this game is ended
+		others := otherRunningSessions(game)
+		if others > 0 {
+			println("Controller wants to close everything, but", others, "other games are running, so only game", game.ID, "is ended")
+		} else {
+			println("Controller wants to close everything")
+
+			// Copy the workers, as they can disconnect while we shut them down
+			workersMutex.Lock()
+			shutdown := make([]*worker, len(workers))
+			copy(shutdown, workers)
+			workersMutex.Unlock()
+
+			for w, worker := range shutdown {
+				println("Disconnecting worker", w)
+				worker.Client.Call(stubs.WorkerShutdown, stubs.Empty{}, &stubs.Empty{})
+				worker.Client.Close()
+			}
 		}
 
-		
-		controller.Call(stubs.ControllerFinalTurnComplete,
-			stubs.BoardStateReport{
-				CompletedTurns: turn,
-				Board:          stubs.BitBoardFromSlice(board, height, width),
-			},
-			&stubs.Empty{})
+		final := stubs.BoardStateReport{
+			CompletedTurns: turn,
+			Board:          stubs.BitBoardFromSlice(runner.Board(), height, width),
+			Ages:           game.agesReport(),
+		}
+		game.observers.end(turn, runner.Board, stubs.ControllerFinalTurnComplete, final)
+		controller.Call(stubs.ControllerFinalTurnComplete, final, &stubs.Empty{})
 
-		// Closing our listener will close our RPC serfver
-		listener.Close()
-		return true
+		// Our listener is closed once the game has stopped, see controllerLoop
+		return true, others == 0
 
 	case 'r':
 	
 		println("Randomising Board")
+		board := runner.Board()
 		randomiseBoard(board, height, width)
+		runner.SetBoard(board)
 	}
-	return false
+	return false, false
 }
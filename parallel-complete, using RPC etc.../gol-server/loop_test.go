@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// runUpdateBoard calculates a turn of a random board with updateBoard, and checks the result is right
+func runUpdateBoard(t *testing.T, height, width int) {
+	board := randomBoard(height, width, 1)
+	newBoard := make([][]bool, height)
+	for row := range newBoard {
+		newBoard[row] = make([]bool, width)
+	}
+	updateBoard(board, newBoard, height, width, 1, stubs.ConwayRule, stubs.Torus)
+	if !sameBoard(newBoard, expectedTurn(board, height, width, stubs.ConwayRule, stubs.Torus)) {
+		t.Error("The board is wrong")
+	}
+}
+
+// TestUpdateBoardFailedWorker checks only the fragment of a worker which fails is given to another worker,
+// and that the worker is disconnected
+func TestUpdateBoardFailedWorker(t *testing.T) {
+	fakes := []*FakeWorker{{fail: true}, {}, {}}
+	_, cleanup := connectFakeWorkers(t, 0, fakes...)
+	defer cleanup()
+
+	runUpdateBoard(t, 30, 20)
+
+	// Each worker is given 10 rows, and the next worker along is given the failed fragment as well
+	expected := []struct{ calls, rows int }{{1, 10}, {2, 20}, {1, 10}}
+	for i, fake := range fakes {
+		if fake.Calls("DoTurn") != expected[i].calls || fake.Rows() != expected[i].rows {
+			t.Errorf("Worker %d: expected %d calls for %d rows, got %d calls for %d rows",
+				i, expected[i].calls, expected[i].rows, fake.Calls("DoTurn"), fake.Rows())
+		}
+	}
+	if connectedWorkers() != 2 {
+		t.Errorf("Expected the failed worker to be disconnected, %d workers are connected", connectedWorkers())
+	}
+}
+
+// TestUpdateBoardSlowWorker checks the fragment of a worker which misses the deadline is given to another worker,
+// but the worker is kept in case it recovers
+func TestUpdateBoardSlowWorker(t *testing.T) {
+	fakes := []*FakeWorker{{delay: time.Second}, {}, {}}
+	_, cleanup := connectFakeWorkers(t, 50*time.Millisecond, fakes...)
+	defer cleanup()
+
+	runUpdateBoard(t, 30, 20)
+
+	if fakes[1].Calls("DoTurn") != 2 || fakes[2].Calls("DoTurn") != 1 {
+		t.Errorf("Expected only the slow worker's fragment to be reassigned, the workers got %d and %d calls",
+			fakes[1].Calls("DoTurn"), fakes[2].Calls("DoTurn"))
+	}
+	if connectedWorkers() != 3 {
+		t.Errorf("Expected the slow worker to stay connected, %d workers are connected", connectedWorkers())
+	}
+}
+
+// TestUpdateBoardNoWorkersLeft checks the server calculates fragments itself when every worker fails
+func TestUpdateBoardNoWorkersLeft(t *testing.T) {
+	fakes := []*FakeWorker{{fail: true}, {fail: true}}
+	_, cleanup := connectFakeWorkers(t, 0, fakes...)
+	defer cleanup()
+
+	runUpdateBoard(t, 30, 20)
+
+	// A fragment can be given to the other worker before it fails, so it may have been sent twice
+	for i, fake := range fakes {
+		if fake.Calls("DoTurn") == 0 {
+			t.Errorf("Worker %d: expected to be sent a fragment", i)
+		}
+	}
+	if connectedWorkers() != 0 {
+		t.Errorf("Expected both workers to be disconnected, %d workers are connected", connectedWorkers())
+	}
+}
+
+// TestUpdateBoardNoWorkers checks the server calculates the whole turn itself without any workers
+func TestUpdateBoardNoWorkers(t *testing.T) {
+	_, cleanup := connectFakeWorkers(t, 0)
+	defer cleanup()
+
+	runUpdateBoard(t, 30, 20)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"net/rpc"
+	"strconv"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file lets controllers watch a game run by someone else
+// Observers get the same reports as the controller, but can't send keypresses
+// Nothing sent to an observer waits for it, so a slow or broken observer can't hold up the game
+
+// observerFrameRate is used for observers which don't ask for a frame rate
+// Observers never get every turn, as that would make the game wait for them
+const observerFrameRate = 30
+
+// observerQueue is the most reports waiting to be sent to an observer, more are dropped
+const observerQueue = 16
+
+// observerCall is a report waiting to be sent to an observer
+type observerCall struct {
+	method string
+	args   interface{}
+}
+
+// observer is a controller watching a game
+type observer struct {
+	ID        string
+	client    *rpc.Client
+	publisher *visualPublisher
+
+	calls chan observerCall
+	done  chan bool
+}
+
+// Send the observer's reports in order until its calls are closed
+// If a call fails the observer has gone, so it is removed from the game
+func (o *observer) run(observers *observerSet) {
+	failed := false
+	for call := range o.calls {
+		if failed {
+			continue
+		}
+		err := o.client.Call(call.method, call.args, &stubs.Empty{})
+		if err != nil {
+			println("Error sending to observer", o.ID, err.Error())
+			failed = true
+			go observers.detach(o.ID)
+		}
+	}
+	close(o.done)
+}
+
+// Wait for the reports already queued to be sent, then disconnect
+func (o *observer) close() {
+	if o.publisher != nil {
+		o.publisher.stop()
+	}
+	close(o.calls)
+	<-o.done
+	o.client.Close()
+}
+
+// observerSet holds the observers of a game
+// A new set is made each time a controller starts the game, and it is closed when the game stops
+type observerSet struct {
+	mutex     sync.Mutex
+	observers map[string]*observer
+	closed    bool
+	nextID    int
+}
+
+func newObserverSet() *observerSet {
+	return &observerSet{observers: make(map[string]*observer)}
+}
+
+// Attach connects to an observer, returning its ID
+// frameRate is the most boards it is sent a second, or zero if it doesn't want visual updates
+func (s *observerSet) attach(address string, height, width, frameRate int) (string, error) {
+	client, err := rpc.Dial("tcp", address)
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		client.Close()
+		return "", errors.New("game has stopped")
+	}
+	s.nextID++
+	o := &observer{
+		ID:     "observer-" + strconv.Itoa(s.nextID),
+		client: client,
+		calls:  make(chan observerCall, observerQueue),
+		done:   make(chan bool),
+	}
+	if frameRate > 0 {
+		o.publisher = newVisualPublisher(client, height, width, frameRate)
+	}
+	s.observers[o.ID] = o
+	go o.run(s)
+	return o.ID, nil
+}
+
+// Detach disconnects an observer, returning false if it wasn't attached
+func (s *observerSet) detach(id string) bool {
+	s.mutex.Lock()
+	o, exists := s.observers[id]
+	delete(s.observers, id)
+	s.mutex.Unlock()
+
+	if !exists {
+		return false
+	}
+	o.close()
+	println("Observer", id, "detached")
+	return true
+}
+
+// Send queues a report for every observer, dropping it for observers which are too far behind
+func (s *observerSet) send(method string, args interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, o := range s.observers {
+		select {
+		case o.calls <- observerCall{method: method, args: args}:
+		default:
+		}
+	}
+}
+
+// Offer the board on a turn to the observers which want visual updates, see visualPublisher.offer
+func (s *observerSet) offer(turn int, board func() [][]bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, o := range s.observers {
+		if o.publisher != nil {
+			o.publisher.offer(turn, board)
+		}
+	}
+}
+
+// Flush sends the board on a turn to the observers which haven't seen it, skipping any still sending their last board
+func (s *observerSet) flush(turn int, board func() [][]bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, o := range s.observers {
+		if o.publisher != nil {
+			o.publisher.flush(turn, board, false)
+		}
+	}
+}
+
+// Count returns the number of observers attached
+func (s *observerSet) count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.observers)
+}
+
+// End sends the board on the last turn and a final report to every observer, then disconnects them
+// The game doesn't wait for this, so the board is copied for the observers to share
+// Nothing can attach once the set has ended, and ending it again does nothing
+func (s *observerSet) end(turn int, board func() [][]bool, method string, args interface{}) {
+	s.mutex.Lock()
+	observers := s.observers
+	s.observers = make(map[string]*observer)
+	s.closed = true
+	s.mutex.Unlock()
+	if len(observers) == 0 {
+		return
+	}
+
+	cells := copyBoard(board())
+	for _, o := range observers {
+		go func(o *observer) {
+			if o.publisher != nil {
+				o.publisher.finish(turn, func() [][]bool { return cells })
+			}
+			o.calls <- observerCall{method: method, args: args}
+			o.close()
+			println("Observer", o.ID, "detached at the end of the game")
+		}(o)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file handles pausing a game, running it a few turns at a time and limiting its speed
+// The state is kept in the session, so the game loop carries on reporting to its controller while it is paused
+
+// always is closed, so it is always ready when there is nothing stopping the next turn
+var always = func() chan time.Time {
+	c := make(chan time.Time)
+	close(c)
+	return c
+}()
+
+// Get a channel which is ready when the game should calculate its next turn
+// It is nil while the game is paused, so the next turn waits until the game is resumed or stepped
+func nextTurn(game *session, lastTurn time.Time) <-chan time.Time {
+	if game.paused {
+		return nil
+	}
+	if game.turnsPerSecond > 0 {
+		wait := time.Until(lastTurn.Add(time.Second / time.Duration(game.turnsPerSecond)))
+		if wait > 0 {
+			return time.After(wait)
+		}
+	}
+	return always
+}
+
+// Pause or resume the game, telling the controller and observers
+// A game which pauses sends its current board for visual updates, so the turn it stopped on is shown
+func setPaused(game *session, controller *controllerLink, paused bool, turn int, board func() [][]bool) {
+	if paused == game.paused {
+		return
+	}
+	game.paused = paused
+	report := stubs.StateChangeReport{Previous: stubs.Executing, New: stubs.Paused, CompletedTurns: turn}
+	if paused {
+		println("Pausing execution at turn", turn)
+		controller.flush(turn, board)
+		game.observers.flush(turn, board)
+	} else {
+		println("Resuming execution at turn", turn)
+		report.Previous, report.New = stubs.Paused, stubs.Executing
+	}
+	game.observers.send(stubs.ControllerGameStateChange, report)
+	controller.call(stubs.ControllerGameStateChange, report)
+}
+
+// Handle a step or speed request from the controller
+// Steps run the game to their turn and then pause it, even if it was running
+func handleCommand(game *session, controller *controllerLink, command interface{}, turn int, board func() [][]bool) {
+	switch c := command.(type) {
+	case stubs.StepRequest:
+		target := turn + c.Turns
+		if c.ToTurn {
+			target = c.Turns
+		} else if c.Turns <= 0 {
+			target = turn + 1
+		}
+		if target <= turn {
+			println("Can't step back to turn", target, "from turn", turn)
+			return
+		}
+		println("Stepping to turn", target)
+		game.stopAt = target
+		setPaused(game, controller, false, turn, board)
+	case stubs.SpeedRequest:
+		game.turnsPerSecond = c.TurnsPerSecond
+		if c.TurnsPerSecond == 0 {
+			println("Running as fast as possible")
+		} else {
+			println("Running at most", c.TurnsPerSecond, "turns a second")
+		}
+	}
+}
+
+// Pause the game if it has reached the turn it was stepped to
+func checkStep(game *session, controller *controllerLink, turn int, board func() [][]bool) {
+	if game.stopAt >= 0 && turn >= game.stopAt {
+		game.stopAt = -1
+		setPaused(game, controller, true, turn, board)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// FakeController records the visual updates it is sent, taking delay to handle each one
+type FakeController struct {
+	delay time.Duration
+
+	mutex   sync.Mutex
+	reports []stubs.BoardStateReport
+}
+
+func (f *FakeController) TurnComplete(req stubs.BoardStateReport, res *stubs.Empty) error {
+	time.Sleep(f.delay)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.reports = append(f.reports, req)
+	return nil
+}
+
+// Reports returns the reports received so far
+func (f *FakeController) Reports() []stubs.BoardStateReport {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]stubs.BoardStateReport(nil), f.reports...)
+}
+
+// Connect a fake controller over a pipe, until the returned function is called
+func connectFakeController(t *testing.T, fake *FakeController) (*rpc.Client, func()) {
+	server := rpc.NewServer()
+	err := server.RegisterName("Controller", fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverEnd, clientEnd := net.Pipe()
+	go server.ServeConn(serverEnd)
+	client := rpc.NewClient(clientEnd)
+	return client, func() { client.Close() }
+}
+
+// publishTurns offers the boards from turn 0 to lastTurn to a publisher, waiting between each one, then finishes it
+// It returns the number of boards the publisher asked for
+func publishTurns(publisher *visualPublisher, height, width, lastTurn int, wait time.Duration) int {
+	asked := 0
+	for turn := 0; turn <= lastTurn; turn++ {
+		board := randomBoard(height, width, int64(turn))
+		publisher.offer(turn, func() [][]bool {
+			asked++
+			return board
+		})
+		time.Sleep(wait)
+	}
+	publisher.finish(lastTurn, func() [][]bool {
+		asked++
+		return randomBoard(height, width, int64(lastTurn))
+	})
+	return asked
+}
+
+// checkReports checks the controller would have the right board after each report,
+// and that SkippedTurns counts the turns since the last report
+func checkReports(t *testing.T, reports []stubs.BoardStateReport, height, width, lastTurn int) {
+	if len(reports) == 0 {
+		t.Fatal("No reports were sent")
+	}
+	var shown [][]bool
+	previous := -1
+	for i, report := range reports {
+		if report.Board != nil {
+			shown = report.Board.ToSlice()
+		} else if shown == nil {
+			t.Fatalf("Report %d has a delta before any board was sent", i)
+		} else if err := report.Delta.Apply(shown); err != nil {
+			t.Fatal(err)
+		}
+		if !sameBoard(shown, randomBoard(height, width, int64(report.CompletedTurns))) {
+			t.Errorf("Report %d: the board on turn %d is wrong", i, report.CompletedTurns)
+		}
+
+		skipped := 0
+		if previous >= 0 {
+			skipped = report.CompletedTurns - previous - 1
+		}
+		if report.CompletedTurns <= previous || report.SkippedTurns != skipped {
+			t.Errorf("Report %d: expected turn %d to skip %d turns after turn %d, it skipped %d",
+				i, report.CompletedTurns, skipped, previous, report.SkippedTurns)
+		}
+		previous = report.CompletedTurns
+	}
+	if previous != lastTurn {
+		t.Errorf("Expected the last report to be turn %d, got %d", lastTurn, previous)
+	}
+}
+
+// TestPublisherSkipsTurns checks boards offered while the controller is busy are dropped without being copied,
+// and that the controller is told how many turns were skipped
+func TestPublisherSkipsTurns(t *testing.T) {
+	const height, width, lastTurn = 16, 16, 99
+	fake := &FakeController{delay: 20 * time.Millisecond}
+	client, cleanup := connectFakeController(t, fake)
+	defer cleanup()
+
+	publisher := newVisualPublisher(client, height, width, 1000)
+	asked := publishTurns(publisher, height, width, lastTurn, time.Millisecond)
+
+	reports := fake.Reports()
+	if len(reports) < 2 || len(reports) > lastTurn/2 {
+		t.Errorf("Expected most of the %d turns to be skipped, %d were sent", lastTurn+1, len(reports))
+	}
+	if asked != len(reports) {
+		t.Errorf("Expected only the %d boards sent to be asked for, %d were", len(reports), asked)
+	}
+	checkReports(t, reports, height, width, lastTurn)
+}
+
+// TestPublisherFrameRate checks boards are dropped until the next frame is due, even if the controller is ready
+func TestPublisherFrameRate(t *testing.T) {
+	const height, width, lastTurn = 16, 16, 49
+	fake := new(FakeController)
+	client, cleanup := connectFakeController(t, fake)
+	defer cleanup()
+
+	// 50 turns 2ms apart take about 100ms, so at 20 frames a second only a few are sent
+	publisher := newVisualPublisher(client, height, width, 20)
+	publishTurns(publisher, height, width, lastTurn, 2*time.Millisecond)
+
+	reports := fake.Reports()
+	if len(reports) < 2 || len(reports) > 10 {
+		t.Errorf("Expected about 3 boards to be sent, %d were", len(reports))
+	}
+	checkReports(t, reports, height, width, lastTurn)
+}
+
+// TestPublisherEveryTurn checks every board is sent without a frame rate, however slow the controller is
+func TestPublisherEveryTurn(t *testing.T) {
+	const height, width, lastTurn = 16, 16, 19
+	fake := &FakeController{delay: 2 * time.Millisecond}
+	client, cleanup := connectFakeController(t, fake)
+	defer cleanup()
+
+	publisher := newVisualPublisher(client, height, width, 0)
+	asked := publishTurns(publisher, height, width, lastTurn, 0)
+
+	reports := fake.Reports()
+	if len(reports) != lastTurn+1 || asked != lastTurn+1 {
+		t.Errorf("Expected all %d boards to be sent, %d were sent and %d asked for", lastTurn+1, len(reports), asked)
+	}
+	checkReports(t, reports, height, width, lastTurn)
+}
+
+// TestDetachHungController checks a controller which never replies to a visual update
+// doesn't stop the game from detaching or closing it
+func TestDetachHungController(t *testing.T) {
+	const height, width = 16, 16
+	for _, detach := range []bool{true, false} {
+		client, cleanup := connectFakeController(t, &FakeController{delay: time.Hour})
+		board := randomBoard(height, width, 1)
+		game := newSession(stubs.StartGameRequest{Board: stubs.BitBoardFromSlice(board, height, width), Height: height, Width: width})
+		controller := newControllerLink(game, client, true, 0)
+		controller.offer(0, func() [][]bool { return board })
+		// Give the publisher time to start sending the board
+		time.Sleep(20 * time.Millisecond)
+
+		done := make(chan bool)
+		go func() {
+			if detach {
+				controller.detach()
+			} else {
+				controller.close()
+			}
+			done <- true
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Detach %v: timed out waiting for the controller to be disconnected", detach)
+		}
+		cleanup()
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"uk.ac.bris.cs/gameoflife/engine"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains the turn runners, which calculate the turns of a game using the engine it asked for
+
+// turnRunner calculates the turns of a game
+// It is only used by the controllerLoop goroutine of its game
+type turnRunner interface {
+	// Step calculates at least the next turn, but never goes past the limit turn
+	Step(limit int)
+	// Board returns the board on the current turn
+	Board() [][]bool
+	// SetBoard replaces the cells on the current turn
+	SetBoard(board [][]bool)
+	// Turn returns the number of turns completed
+	Turn() int
+	// Split returns how the board was split between workers on the last turn
+	Split() []stubs.StripStatus
+	// Close releases anything held for the game, such as strips on the workers
+	Close()
+}
+
+// tileReporter is implemented by runners which split the board into tiles
+type tileReporter interface {
+	// ActiveTiles returns the number of tiles calculated on the last turn and the total number of tiles
+	ActiveTiles() (active, total int)
+}
+
+// Create the turn runner for the engine a game is using
+func newRunner(game *session) turnRunner {
+	switch game.engine {
+	case stubs.Stateful:
+		return newStatefulRunner(game)
+	case stubs.Sparse:
+		return &sparseRunner{
+			universe: engine.NewSparse(game.board, game.height, game.width, game.rule, game.topology),
+			turn:     game.turn,
+			threads:  game.threads,
+			height:   game.height,
+		}
+	case stubs.HashLife:
+		universe, err := engine.NewHashLife(game.board, game.height, game.width, game.rule)
+		if err == nil {
+			return &hashLifeRunner{universe: universe, turn: game.turn, height: game.height}
+		}
+		println("Can't use HashLife:", err.Error())
+		return newHaloRunner(game)
+	default:
+		return newHaloRunner(game)
+	}
+}
+
+// haloRunner sends the whole board out to the workers every turn
+type haloRunner struct {
+	board    [][]bool
+	newBoard [][]bool
+	turn     int
+	split    []stubs.StripStatus
+
+	height, width int
+	threads       int
+	rule          stubs.Rule
+	topology      stubs.Topology
+}
+
+// Create a halo runner which works on the board of a game
+func newHaloRunner(game *session) *haloRunner {
+	newBoard := make([][]bool, game.height)
+	for row := 0; row < game.height; row++ {
+		newBoard[row] = make([]bool, game.width)
+	}
+	return &haloRunner{
+		board:    game.board,
+		newBoard: newBoard,
+		turn:     game.turn,
+
+		height:   game.height,
+		width:    game.width,
+		threads:  game.threads,
+		rule:     game.rule,
+		topology: game.topology,
+	}
+}
+
+func (r *haloRunner) Step(limit int) {
+	// Get the next board state (this will send calls to workers)
+	r.split = updateBoard(r.board, r.newBoard, r.height, r.width, r.threads, r.rule, r.topology)
+
+	for row := 0; row < r.height; row++ {
+		copy(r.board[row], r.newBoard[row])
+	}
+	r.turn++
+}
+
+func (r *haloRunner) Board() [][]bool {
+	return r.board
+}
+
+func (r *haloRunner) SetBoard(board [][]bool) {
+	for row := 0; row < r.height; row++ {
+		copy(r.board[row], board[row])
+	}
+}
+
+func (r *haloRunner) Turn() int {
+	return r.turn
+}
+
+func (r *haloRunner) Split() []stubs.StripStatus {
+	return r.split
+}
+
+func (r *haloRunner) Close() {}
+
+// hashLifeRunner calculates turns on the server using HashLife
+// It jumps as many turns as it can at once, so long games finish quickly
+type hashLifeRunner struct {
+	universe *engine.HashLife
+	turn     int
+	height   int
+}
+
+func (r *hashLifeRunner) Step(limit int) {
+	// Jump by the biggest power of two which doesn't go past the limit
+	turns := 1
+	for r.turn+turns*2 <= limit {
+		turns *= 2
+	}
+	r.universe.Advance(turns)
+	r.turn += turns
+}
+
+func (r *hashLifeRunner) Board() [][]bool {
+	return r.universe.Board()
+}
+
+func (r *hashLifeRunner) SetBoard(board [][]bool) {
+	r.universe.SetBoard(board)
+}
+
+func (r *hashLifeRunner) Turn() int {
+	return r.turn
+}
+
+func (r *hashLifeRunner) Split() []stubs.StripStatus {
+	return []stubs.StripStatus{{StartRow: 0, EndRow: r.height}}
+}
+
+func (r *hashLifeRunner) Close() {}
+
+// sparseRunner calculates turns on the server, skipping the parts of the board which can't change
+type sparseRunner struct {
+	universe *engine.Sparse
+	turn     int
+	threads  int
+	height   int
+}
+
+func (r *sparseRunner) Step(limit int) {
+	r.universe.Step(r.threads)
+	r.turn++
+}
+
+func (r *sparseRunner) Board() [][]bool {
+	return r.universe.Board()
+}
+
+func (r *sparseRunner) SetBoard(board [][]bool) {
+	r.universe.SetBoard(board)
+}
+
+func (r *sparseRunner) Turn() int {
+	return r.turn
+}
+
+func (r *sparseRunner) Split() []stubs.StripStatus {
+	return []stubs.StripStatus{{StartRow: 0, EndRow: r.height}}
+}
+
+func (r *sparseRunner) ActiveTiles() (active, total int) {
+	return r.universe.ActiveTiles()
+}
+
+func (r *sparseRunner) Close() {}
@@ -4,32 +4,44 @@ import (
 	"flag"
 	"net"
 	"net/rpc"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
+	"uk.ac.bris.cs/gameoflife/engine"
 	"uk.ac.bris.cs/gameoflife/stubs"
 )
 
 type worker struct {
 	Client  *rpc.Client
 	Address string
+
+	// Average throughput of the worker, used to decide how many rows it gets
+	mutex         sync.Mutex
+	rowsPerSecond float64
 }
 
 // Global variables
 var (
-	controller      *rpc.Client
-	controllerMutex sync.Mutex
-	lastBoardState  [][]bool
-	lastTurn        int
+	sessions      map[string]*session
+	sessionsMutex sync.Mutex
+	lastGameID    string
 
 	workers      []*worker
 	workersMutex sync.Mutex
-	keypresses   chan rune
 	listener     net.Listener
+
+	checkpointDir      string
+	checkpointInterval time.Duration
+	orphanTimeout      time.Duration
+	callDeadline       time.Duration
+	rebalanceInterval  time.Duration
 )
 
 // Setup variables on program start
 func init() {
-	keypresses = make(chan rune, 10)
+	sessions = make(map[string]*session)
 	workers = make([]*worker, 0)
 }
 
@@ -37,28 +49,12 @@ func init() {
 type Server struct{}
 
 // StartGame is called by the controller when it wants to connect and start a game
-func (s *Server) StartGame(req stubs.StartGameRequest, res *stubs.ServerResponse) (err error) {
-	// Lock the controller until we have finished
-	controllerMutex.Lock()
-	defer controllerMutex.Unlock()
+// Each game gets its own session, so several controllers can run games at the same time
+// A controller can resume a game which has stopped, or take over one which is still running after its controller disconnected
+func (s *Server) StartGame(req stubs.StartGameRequest, res *stubs.StartGameResponse) (err error) {
 	println("Received request to start a game")
-	
-	if controller != nil {
-		println("We already have a controller")
-		res.Message = "Server already has a controller"
-		res.Success = false
-		return
-	}
-
-	
-	if len(workers) == 0 {
-		println("We have no workers available")
-		res.Message = "Server has no workers"
-		res.Success = false
-		return
-	}
 
-	
+	// Connect to the controller before locking the sessions, so one which can't be reached doesn't hold up every other game
 	newController, err := rpc.Dial("tcp", req.ControllerAddress)
 	if err != nil {
 		println("Error connecting to controller: ", err.Error())
@@ -66,57 +62,299 @@ func (s *Server) StartGame(req stubs.StartGameRequest, res *stubs.ServerResponse
 		res.Success = false
 		return err
 	}
+	// The connection is closed unless the game takes it
+	defer func() {
+		if !res.Success {
+			newController.Close()
+		}
+	}()
+
+	// Lock the sessions until we have finished
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
 
-	var newBoard [][]bool
-	startTurn := 0
+	var game *session
 	if req.StartNew {
 		println("Starting a new game!")
-		newBoard = req.Board.ToSlice()
+		game = newSession(req)
 	} else {
-		println("Client resuming previous game")
-	
-		if lastBoardState == nil {
-		
+		println("Client resuming previous game", req.GameID)
+
+		game = findSession(req.GameID)
+		if game == nil {
 			println("Error resuming board: no previous board")
 			res.Message = "Error resuming: no previous board"
 			res.Success = false
 			return
 		}
 
+		if game.controller != nil {
+			println("Game", game.ID, "already has a controller")
+			res.Message = "Error resuming: game already has a controller"
+			res.Success = false
+			return
+		}
+
 		// Continue with the previous
-		// Make sure height and width match
-		if req.Height != len(lastBoardState) || req.Width != len(lastBoardState[0]) {
+		// Make sure height and width match, if the controller knows them
+		if (req.Height != 0 || req.Width != 0) && (req.Height != game.height || req.Width != game.width) {
 			println("Error resuming board: controller has the wrong height and width")
 			res.Message = "Error resuming: controller had the wrong height and width"
 			res.Success = false
 			return
 		}
-		// Copy the last board state
-		newBoard = make([][]bool, req.Height)
-		for row := 0; row < req.Height; row++ {
-			newBoard[row] = make([]bool, req.Width)
-			copy(newBoard[row], lastBoardState[row])
+	}
+
+	// A running game keeps its engine, as the runner can't be changed part way through
+	engineUsed := req.Engine
+	if game.running {
+		engineUsed = game.engine
+	}
+	// HashLife and Sparse run on the server, every other engine needs workers
+	workersMutex.Lock()
+	noWorkers := len(workers) == 0
+	workersMutex.Unlock()
+	if noWorkers && engineUsed != stubs.HashLife && engineUsed != stubs.Sparse {
+		println("We have no workers available")
+		res.Message = "Server has no workers"
+		res.Success = false
+		return
+	}
+	if engineUsed == stubs.HashLife && !game.running {
+		err = engine.CheckHashLife(game.height, game.width, game.topology)
+		if err != nil {
+			println("Error starting game:", err.Error())
+			res.Message = err.Error()
+			res.Success = false
+			return nil
 		}
-		println("Resuming at turn ", lastTurn)
-		startTurn = lastTurn
 	}
 
 	// If successful store the controller reference
-	controller = newController
-	println("Controller connected")
+	game.controller = newController
+	sessions[game.ID] = game
+	println("Controller connected to game", game.ID)
 	res.Success = true
 	res.Message = "Connected!"
+	res.GameID = game.ID
+	res.Height, res.Width = game.height, game.width
+	res.Rule, res.Topology = game.rule, game.topology
+
+	if game.running {
+		// The game loop sends the new controller the board and carries on
+		println("Taking over running game at turn", game.splitTurn)
+		res.Turn = game.splitTurn
+		game.attachments <- attachment{
+			controller:    newController,
+			maxTurns:      req.MaxTurns,
+			visualUpdates: req.VisualUpdates,
+			frameRate:     req.FrameRate,
+			trackAges:     req.TrackAges,
+			onDisconnect:  req.OnDisconnect,
+		}
+		return
+	}
+
+	if !req.StartNew {
+		println("Resuming at turn ", game.turn)
+	}
+	res.Turn = game.turn
+	game.engine = req.Engine
+	game.maxTurns = req.MaxTurns
+	game.threads = req.Threads
+	game.visualUpdates = req.VisualUpdates
+	game.trackAges = req.TrackAges
+	game.frameRate = req.FrameRate
+	game.onDisconnect = req.OnDisconnect
+	game.observers = newObserverSet()
+	game.running = true
 
 	// Run the controller loop goroutine
-	go controllerLoop(newBoard, startTurn, req.Height, req.Width, req.MaxTurns, req.Threads, req.VisualUpdates)
+	println("Using rule", game.rule.String(), "on a", game.topology.String())
+	go controllerLoop(game)
 	return
 }
 
 // RegisterKeypress is called by controller when a key is pressed on their SDL window
 func (s *Server) RegisterKeypress(req stubs.KeypressRequest, res *stubs.ServerResponse) (err error) {
-	println("Received keypress request")
-	// Send the keypress down down the keypresses channel
-	keypresses <- req.Key
+	println("Received keypress request for game", req.GameID)
+	sessionsMutex.Lock()
+	game, exists := sessions[req.GameID]
+	if !exists || game.controller == nil {
+		sessionsMutex.Unlock()
+		res.Message = "Game is not running"
+		res.Success = false
+		return
+	}
+	sessionsMutex.Unlock()
+
+	// Send the keypress down the game's keypresses channel
+	select {
+	case game.keypresses <- req.Key:
+		res.Success = true
+	default:
+		res.Message = "Too many keypresses waiting"
+		res.Success = false
+	}
+	return
+}
+
+// Step is called by a controller to run its game for some turns and then pause it
+// Jumps to a turn the game has already reached are refused here, so the controller can say why
+func (s *Server) Step(req stubs.StepRequest, res *stubs.ServerResponse) (err error) {
+	if req.ToTurn {
+		sessionsMutex.Lock()
+		turn := -1
+		if game, exists := sessions[req.GameID]; exists && game.running {
+			turn = game.splitTurn
+		}
+		sessionsMutex.Unlock()
+		if req.Turns <= turn {
+			res.Message = "Can't jump back to turn " + strconv.Itoa(req.Turns) + ", the game is on turn " + strconv.Itoa(turn)
+			res.Success = false
+			return
+		}
+	}
+	sendCommand(req.GameID, req, res)
+	return
+}
+
+// SetSpeed is called by a controller to limit how many turns its game calculates a second
+func (s *Server) SetSpeed(req stubs.SpeedRequest, res *stubs.ServerResponse) (err error) {
+	if req.TurnsPerSecond < 0 {
+		res.Message = "Turns per second can't be negative"
+		res.Success = false
+		return
+	}
+	sendCommand(req.GameID, req, res)
+	return
+}
+
+// Pass a command to the loop of a game with a controller, like a keypress
+func sendCommand(gameID string, command interface{}, res *stubs.ServerResponse) {
+	sessionsMutex.Lock()
+	game, exists := sessions[gameID]
+	if !exists || game.controller == nil {
+		sessionsMutex.Unlock()
+		res.Message = "Game is not running"
+		res.Success = false
+		return
+	}
+	sessionsMutex.Unlock()
+
+	select {
+	case game.commands <- command:
+		res.Success = true
+	default:
+		res.Message = "Too many commands waiting"
+		res.Success = false
+	}
+}
+
+// Observe is called by a controller which wants to watch a running game
+// Observers get the alive counts, state changes and boards at their own frame rate, but can't send keypresses
+func (s *Server) Observe(req stubs.ObserveRequest, res *stubs.ObserveResponse) (err error) {
+	sessionsMutex.Lock()
+	game := findRunningSession(req.GameID)
+	if game == nil {
+		sessionsMutex.Unlock()
+		println("No running game to observe")
+		res.Message = "No running game to observe"
+		if req.GameID == "" {
+			res.Message += ", give the ID of one of the running games"
+		}
+		res.Success = false
+		return
+	}
+	// Make sure height and width match, if the observer knows them
+	if (req.Height != 0 || req.Width != 0) && (req.Height != game.height || req.Width != game.width) {
+		sessionsMutex.Unlock()
+		println("Error observing: observer has the wrong height and width")
+		res.Message = "Error observing: observer had the wrong height and width"
+		res.Success = false
+		return
+	}
+	observers, height, width, turn := game.observers, game.height, game.width, game.splitTurn
+	rule, topology := game.rule, game.topology
+	sessionsMutex.Unlock()
+
+	frameRate := 0
+	if req.VisualUpdates {
+		frameRate = req.FrameRate
+		if frameRate <= 0 {
+			frameRate = observerFrameRate
+		}
+	}
+	id, err := observers.attach(req.ObserverAddress, height, width, frameRate)
+	if err != nil {
+		println("Error connecting to observer:", err.Error())
+		res.Message = "Failed to connect to observer: " + err.Error()
+		res.Success = false
+		return nil
+	}
+
+	println("Observer", id, "watching game", game.ID)
+	res.Success = true
+	res.Message = "Watching!"
+	res.GameID = game.ID
+	res.ObserverID = id
+	res.Turn = turn
+	res.Height, res.Width = height, width
+	res.Rule, res.Topology = rule, topology
+	return
+}
+
+// Detach is called by an observer when it stops watching a game
+func (s *Server) Detach(req stubs.DetachRequest, res *stubs.ServerResponse) (err error) {
+	sessionsMutex.Lock()
+	game, exists := sessions[req.GameID]
+	var observers *observerSet
+	if exists {
+		observers = game.observers
+	}
+	sessionsMutex.Unlock()
+
+	if observers == nil || !observers.detach(req.ObserverID) {
+		res.Message = "Not watching this game"
+		res.Success = false
+		return
+	}
+	res.Success = true
+	return
+}
+
+// Status is called to see how fast each worker is and how the games are split between them
+func (s *Server) Status(req stubs.Empty, res *stubs.StatusResponse) (err error) {
+	workersMutex.Lock()
+	for _, worker := range workers {
+		res.Workers = append(res.Workers, stubs.WorkerStatus{Address: worker.Address, RowsPerSecond: worker.rate()})
+	}
+	workersMutex.Unlock()
+
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	for _, game := range sessions {
+		status := stubs.GameStatus{
+			GameID:  game.ID,
+			Running: game.running,
+			Engine:  game.engine,
+			Turn:    game.splitTurn,
+			Height:  game.height,
+			Width:   game.width,
+			Strips:  game.split,
+		}
+		if game.observers != nil {
+			status.Observers = game.observers.count()
+		}
+		// The turn can only be read when the game isn't running
+		if !status.Running {
+			status.Turn = game.turn
+		}
+		res.Games = append(res.Games, status)
+	}
+	sort.Slice(res.Games, func(i, j int) bool {
+		return res.Games[i].GameID < res.Games[j].GameID
+	})
 	return
 }
 
@@ -173,10 +411,28 @@ func (s *Server) Ping(req stubs.Empty, res *stubs.Empty) (err error) {
 func main() {
 
 	portPtr := flag.String("p", "8020", "port to listen on")
+	flag.StringVar(&checkpointDir, "checkpoints", "checkpoints", "directory to store checkpoints in, empty to disable")
+	flag.DurationVar(&checkpointInterval, "checkpoint-interval", 30*time.Second, "how often to checkpoint a running game")
+	flag.DurationVar(&orphanTimeout, "orphan-timeout", 10*time.Minute, "how long a game keeps running without a controller before it pauses, 0 to run until it finishes")
+	flag.DurationVar(&callDeadline, "deadline", 10*time.Second, "how long a worker has to return a fragment before it is given to another worker, 0 to wait forever")
+	flag.DurationVar(&rebalanceInterval, "rebalance-interval", 10*time.Second, "how often stateful games are split again to match worker speeds, 0 to disable")
 	flag.Parse()
 	println("Started server")
 	println("Our RPC port:", *portPtr)
 
+	// Restore games from disk so controllers can resume them
+	if checkpointDir != "" {
+		checkpoints, err := loadCheckpoints(checkpointDir)
+		if err != nil {
+			println("Error loading checkpoints:", err.Error())
+		}
+		for _, cp := range checkpoints {
+			sessions[cp.GameID] = sessionFromCheckpoint(cp)
+			lastGameID = cp.GameID
+			println("Loaded checkpoint of", cp.Width, "x", cp.Height, "game", cp.GameID, "at turn", cp.Turn)
+		}
+	}
+
 	
 	rpc.Register(&Server{})
 
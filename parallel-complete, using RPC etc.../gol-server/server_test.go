@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/rpc"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestStep checks jumps back to a turn the game has passed are refused with a message, and other steps reach the game
+func TestStep(t *testing.T) {
+	game := &session{ID: "step", running: true, controller: new(rpc.Client), commands: make(chan interface{}, 10), splitTurn: 50}
+	sessionsMutex.Lock()
+	sessions[game.ID] = game
+	sessionsMutex.Unlock()
+	defer func() {
+		sessionsMutex.Lock()
+		delete(sessions, game.ID)
+		sessionsMutex.Unlock()
+	}()
+
+	tests := []struct {
+		name    string
+		req     stubs.StepRequest
+		ok      bool
+		message string
+	}{
+		{"jump back", stubs.StepRequest{GameID: "step", Turns: 30, ToTurn: true}, false, "Can't jump back to turn 30, the game is on turn 50"},
+		{"jump to the current turn", stubs.StepRequest{GameID: "step", Turns: 50, ToTurn: true}, false, "on turn 50"},
+		{"jump forward", stubs.StepRequest{GameID: "step", Turns: 51, ToTurn: true}, true, ""},
+		{"step", stubs.StepRequest{GameID: "step", Turns: 5}, true, ""},
+		{"step without a count", stubs.StepRequest{GameID: "step"}, true, ""},
+		{"unknown game", stubs.StepRequest{GameID: "unknown", Turns: 100, ToTurn: true}, false, "not running"},
+	}
+	for _, test := range tests {
+		queued := len(game.commands)
+		res := new(stubs.ServerResponse)
+		err := new(Server).Step(test.req, res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Success != test.ok || !strings.Contains(res.Message, test.message) {
+			t.Errorf("%s: expected success %v with message %q, got %v with %q", test.name, test.ok, test.message, res.Success, res.Message)
+		}
+		if sent := len(game.commands) > queued; sent != test.ok {
+			t.Errorf("%s: expected the step to be sent to the game: %v", test.name, test.ok)
+		}
+	}
+}
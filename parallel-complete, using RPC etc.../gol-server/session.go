@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/rpc"
+
+	"uk.ac.bris.cs/gameoflife/engine"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains the session structure, which lets the server run several games at once
+
+// session stores everything about one game running on the server
+// The board and turn are owned by the controllerLoop goroutine while the game is running
+// All other access must hold sessionsMutex and check the game isn't running
+// The game keeps running if its controller disconnects, so controller can be nil while running is set
+type session struct {
+	ID         string
+	controller *rpc.Client
+	keypresses chan rune
+	running    bool
+
+	// Controllers resuming the game while it is running are passed to controllerLoop
+	attachments chan attachment
+	// Step and speed requests for controllerLoop, see pause.go
+	commands chan interface{}
+
+	// Whether the game is paused, the turn it is being stepped to, and the most turns it calculates a second
+	// These are owned by the controllerLoop goroutine, stopAt is -1 and turnsPerSecond 0 if they aren't set
+	paused         bool
+	stopAt         int
+	turnsPerSecond int
+
+	// Controllers watching the game, a new set is made each time a controller starts it
+	observers *observerSet
+
+	board    [][]bool
+	turn     int
+	height   int
+	width    int
+	rule     stubs.Rule
+	topology stubs.Topology
+
+	engine        stubs.Engine
+	maxTurns      int
+	threads       int
+	visualUpdates bool
+	frameRate     int
+	trackAges     bool
+	onDisconnect  stubs.DisconnectPolicy
+
+	// How long each cell has been alive, if the controller asked for ages
+	// They are counted from when the controller connected, and are owned by the controllerLoop goroutine
+	ages *engine.Ages
+
+	// The split of the board on the last turn, for the status RPC
+	// These are only accessed while holding sessionsMutex
+	split     []stubs.StripStatus
+	splitTurn int
+}
+
+// attachment is a controller resuming a running game, with the settings from its request
+type attachment struct {
+	controller    *rpc.Client
+	maxTurns      int
+	visualUpdates bool
+	frameRate     int
+	trackAges     bool
+	onDisconnect  stubs.DisconnectPolicy
+}
+
+// Create a new session for a game starting with the board in the request
+func newSession(req stubs.StartGameRequest) *session {
+	rule := req.Rule
+	if !req.RuleSet {
+		rule = stubs.ConwayRule
+	}
+	return &session{
+		ID:          newGameID(),
+		keypresses:  make(chan rune, 10),
+		attachments: make(chan attachment, 1),
+		commands:    make(chan interface{}, 10),
+		stopAt:      -1,
+
+		board:    req.Board.ToSlice(),
+		turn:     0,
+		height:   req.Height,
+		width:    req.Width,
+		rule:     rule,
+		topology: req.Topology,
+	}
+}
+
+// Create a session from a checkpoint so it can be resumed
+func sessionFromCheckpoint(cp *checkpoint) *session {
+	return &session{
+		ID:          cp.GameID,
+		keypresses:  make(chan rune, 10),
+		attachments: make(chan attachment, 1),
+		commands:    make(chan interface{}, 10),
+		stopAt:      -1,
+
+		board:    cp.Board.ToSlice(),
+		turn:     cp.Turn,
+		height:   cp.Height,
+		width:    cp.Width,
+		rule:     cp.Rule,
+		topology: cp.Topology,
+	}
+}
+
+// Record the split of the board used for a turn so it can be shown by the status RPC
+func (game *session) setSplit(turn int, split []stubs.StripStatus) {
+	sessionsMutex.Lock()
+	game.split = split
+	game.splitTurn = turn
+	sessionsMutex.Unlock()
+}
+
+// Get the ages of the cells to send with the board, or nil if they aren't being tracked
+func (game *session) agesReport() [][]int16 {
+	if game.ages == nil {
+		return nil
+	}
+	return game.ages.Slice()
+}
+
+// Generate a random ID for a new game
+// sessionsMutex must be held so the ID can be checked against existing games
+func newGameID() string {
+	for {
+		bytes := make([]byte, 4)
+		_, err := rand.Read(bytes)
+		if err != nil {
+			panic(err)
+		}
+		id := hex.EncodeToString(bytes)
+		if _, exists := sessions[id]; !exists {
+			return id
+		}
+	}
+}
+
+// Find the session a controller wants to resume
+// If no ID is given, the most recently stopped game is used
+// sessionsMutex must be held
+func findSession(id string) *session {
+	if id == "" {
+		id = lastGameID
+	}
+	return sessions[id]
+}
+
+// Find the running session an observer wants to watch
+// If no ID is given, the only running game is used, or nil if there isn't exactly one
+// sessionsMutex must be held
+func findRunningSession(id string) *session {
+	if id != "" {
+		game := sessions[id]
+		if game == nil || !game.running {
+			return nil
+		}
+		return game
+	}
+
+	var running *session
+	for _, game := range sessions {
+		if !game.running {
+			continue
+		}
+		if running != nil {
+			return nil
+		}
+		running = game
+	}
+	return running
+}
+
+// Count the games running other than this one, which share its workers
+func otherRunningSessions(game *session) int {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	others := 0
+	for _, other := range sessions {
+		if other != game && other.running {
+			others++
+		}
+	}
+	return others
+}
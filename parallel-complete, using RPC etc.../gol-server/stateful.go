@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"net/rpc"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/engine"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains the stateful runner, which leaves each strip of the board on a worker
+// The workers swap the rows at the edges of their strips with each other every turn,
+// so only a StripTurn call goes through the server and the board is collected only when needed
+
+// statefulRunner keeps a game's board spread across the workers
+type statefulRunner struct {
+	gameID        string
+	height, width int
+	threads       int
+	rule          stubs.Rule
+	topology      stubs.Topology
+
+	// The workers holding each strip, nil if the strips aren't loaded
+	strips   []*worker
+	bounds   []int
+	loadedAt time.Time
+	turn     int
+	split    []stubs.StripStatus
+
+	// The last board collected from the workers, which we go back to if a worker fails
+	board     [][]bool
+	boardTurn int
+
+	// Set when the workers missed the deadline, so the next turn is calculated here before the strips are loaded again
+	// Slow workers are kept, so this makes sure the game moves on even if they keep missing it
+	missedDeadline bool
+}
+
+// Create a stateful runner for a game
+// The strips are loaded onto the workers when the first turn is calculated
+func newStatefulRunner(game *session) *statefulRunner {
+	return &statefulRunner{
+		gameID:   game.ID,
+		height:   game.height,
+		width:    game.width,
+		threads:  game.threads,
+		rule:     game.rule,
+		topology: game.topology,
+
+		turn:      game.turn,
+		board:     game.board,
+		boardTurn: game.turn,
+	}
+}
+
+func (r *statefulRunner) Step(limit int) {
+	r.advance(r.turn + 1)
+
+	if r.strips != nil && rebalanceInterval > 0 && time.Since(r.loadedAt) > rebalanceInterval {
+		r.rebalance()
+	}
+}
+
+// Split the board again if the worker speeds have changed or new workers have connected
+// The strips are collected and reloaded with the new split on the next turn
+func (r *statefulRunner) rebalance() {
+	r.loadedAt = time.Now()
+	available := availableWorkers(r.height)
+
+	changed := len(available) != len(r.strips)
+	for s := 0; s < len(available) && !changed; s++ {
+		changed = available[s] != r.strips[s]
+	}
+	if !changed && !splitChanged(r.bounds, splitRows(r.height, available), r.height) {
+		return
+	}
+
+	println("Rebalancing strips of game", r.gameID)
+	r.Board()
+	r.Close()
+	r.strips = nil
+}
+
+// Board collects the strips from the workers if the board we have is out of date
+func (r *statefulRunner) Board() [][]bool {
+	for r.boardTurn != r.turn {
+		err := r.collect()
+		if err != nil {
+			// Rebuild the board from the last one we have
+			println("Error collecting board:", err.Error())
+			target := r.turn
+			r.reset()
+			r.missedDeadline = err == errDeadline
+			r.advance(target)
+		}
+	}
+	return r.board
+}
+
+func (r *statefulRunner) SetBoard(board [][]bool) {
+	r.Board()
+	for row := 0; row < r.height; row++ {
+		copy(r.board[row], board[row])
+	}
+	// The workers have the old cells, so they need new strips
+	r.Close()
+	r.strips = nil
+}
+
+func (r *statefulRunner) Turn() int {
+	return r.turn
+}
+
+func (r *statefulRunner) Split() []stubs.StripStatus {
+	return r.split
+}
+
+func (r *statefulRunner) Close() {
+	for _, worker := range r.strips {
+		callWorker(worker, stubs.WorkerDropStrip, stubs.StripRequest{GameID: r.gameID}, &stubs.Empty{})
+	}
+}
+
+// Calculate turns until the target turn is reached
+// If a worker fails the strips are reloaded from the last board and the lost turns are calculated again
+func (r *statefulRunner) advance(target int) {
+	for r.turn < target {
+		if r.strips == nil && !r.missedDeadline {
+			r.load()
+		}
+
+		if r.strips == nil {
+			newBoard := make([][]bool, r.height)
+			for row := 0; row < r.height; row++ {
+				newBoard[row] = make([]bool, r.width)
+			}
+			if r.missedDeadline {
+				// The same workers would probably miss it again, so none of them are used for this turn
+				println("Workers missed the deadline, calculating turn", r.turn, "locally")
+				r.missedDeadline = false
+				halo := makeHalo(0, r.height, r.height, r.width, r.board, r.topology)
+				copyFragment(newBoard, engine.DoTurn(halo, r.threads, r.rule, r.topology))
+				r.split = splitStatus([]int{0, r.height}, nil)
+			} else {
+				// The strips couldn't be loaded, so the turn is shared out like a stateless game
+				r.split = updateBoard(r.board, newBoard, r.height, r.width, r.threads, r.rule, r.topology)
+			}
+			r.board = newBoard
+			r.turn++
+			r.boardTurn = r.turn
+			continue
+		}
+
+		err := r.stripTurn()
+		if err != nil {
+			println("Error calculating turn", r.turn, "on the workers:", err.Error())
+			println("Going back to turn", r.boardTurn)
+			r.reset()
+			r.missedDeadline = err == errDeadline
+			continue
+		}
+		r.turn++
+		r.split = splitStatus(r.bounds, r.strips)
+	}
+}
+
+// Forget the strips on the workers and go back to the last board we collected
+func (r *statefulRunner) reset() {
+	r.Close()
+	r.strips = nil
+	r.turn = r.boardTurn
+}
+
+// Split the board into strips and give one to each worker, faster workers getting more rows
+// If there are no workers (or loading fails) the strips are left unloaded
+func (r *statefulRunner) load() {
+	available := availableWorkers(r.height)
+	numStrips := len(available)
+	if numStrips == 0 {
+		return
+	}
+	bounds := splitRows(r.height, available)
+
+	requests := make([]stubs.LoadStripRequest, numStrips)
+	for s := 0; s < numStrips; s++ {
+		start, end := bounds[s], bounds[s+1]
+
+		requests[s] = stubs.LoadStripRequest{
+			GameID: r.gameID,
+			Turn:   r.turn,
+			Strip: stubs.Fragment{
+				StartRow: start,
+				EndRow:   end,
+				BitBoard: stubs.BitBoardFromSlice(r.board[start:end], end-start, r.width),
+			},
+			Threads:  r.threads,
+			Rule:     r.rule,
+			Topology: r.topology,
+			Up:       r.edgeSource(available, s-1, true),
+			Down:     r.edgeSource(available, s+1, false),
+		}
+	}
+
+	err := r.callAll(available, func(s int, worker *worker) error {
+		return callWorker(worker, stubs.WorkerLoadStrip, requests[s], &stubs.Empty{})
+	})
+	if err != nil {
+		for _, worker := range available {
+			callWorker(worker, stubs.WorkerDropStrip, stubs.StripRequest{GameID: r.gameID}, &stubs.Empty{})
+		}
+		return
+	}
+	r.strips = available
+	r.bounds = bounds
+	r.loadedAt = time.Now()
+}
+
+// Find where the row next to a strip comes from
+// neighbour is the strip on the other side of the edge, which may be off the top or bottom of the board
+func (r *statefulRunner) edgeSource(available []*worker, neighbour int, bottom bool) stubs.EdgeSource {
+	if neighbour >= 0 && neighbour < len(available) {
+		return stubs.EdgeSource{Address: available[neighbour].Address, Bottom: bottom}
+	}
+	if !r.topology.WrapsRows() {
+		return stubs.EdgeSource{Dead: true}
+	}
+
+	// Wrap around to the strip at the other end of the board
+	if neighbour < 0 {
+		neighbour = len(available) - 1
+	} else {
+		neighbour = 0
+	}
+	return stubs.EdgeSource{
+		Address: available[neighbour].Address,
+		Bottom:  bottom,
+		Mirror:  r.topology == stubs.KleinBottle,
+	}
+}
+
+// Ask every worker to calculate the next turn of its strip
+// errDeadline is returned if the only problem was a worker missing the deadline
+func (r *statefulRunner) stripTurn() error {
+	return r.callAll(r.strips, func(s int, worker *worker) error {
+		start := time.Now()
+		err := callWorker(worker, stubs.WorkerStripTurn, stubs.StripTurnRequest{GameID: r.gameID, Turn: r.turn}, &stubs.Empty{})
+		if err == nil {
+			worker.recordTurn(r.bounds[s+1]-r.bounds[s], time.Since(start))
+		} else if err == errDeadline {
+			// Count the missed deadline so a slow worker is given fewer rows when the strips are loaded again
+			worker.recordTurn(r.bounds[s+1]-r.bounds[s], callDeadline)
+		}
+		return err
+	})
+}
+
+// Get the strips from every worker and put them together into a board
+func (r *statefulRunner) collect() error {
+	responses := make([]stubs.StripResponse, len(r.strips))
+	err := r.callAll(r.strips, func(s int, worker *worker) error {
+		err := callWorker(worker, stubs.WorkerGetStrip, stubs.StripRequest{GameID: r.gameID}, &responses[s])
+		if err == nil && responses[s].Turn != r.turn {
+			err = errors.New("strip is on the wrong turn")
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	board := make([][]bool, r.height)
+	for row := 0; row < r.height; row++ {
+		board[row] = make([]bool, r.width)
+	}
+	for _, response := range responses {
+		copyFragment(board, response.Strip)
+	}
+	r.board = board
+	r.boardTurn = r.turn
+	return nil
+}
+
+// Run a function for every strip at the same time and wait for them all to finish
+// Workers which couldn't be reached are disconnected, and an error is returned if anything failed
+// If the only errors were missed deadlines errDeadline is returned
+func (r *statefulRunner) callAll(strips []*worker, call func(s int, worker *worker) error) error {
+	errs := make(chan error, len(strips))
+	for s, w := range strips {
+		go func(s int, w *worker) {
+			err := call(s, w)
+			if err != nil {
+				println("Error from worker", w.Address+":", err.Error())
+				// An error returned by the worker itself means it is still there, and a slow worker might recover,
+				// so like in updateBoard they are kept
+				if _, returned := err.(rpc.ServerError); !returned && err != errDeadline {
+					disconnectWorker(w)
+				}
+			}
+			errs <- err
+		}(s, w)
+	}
+
+	var failure error
+	for range strips {
+		err := <-errs
+		if err != nil && (failure == nil || failure == errDeadline) {
+			failure = err
+		}
+	}
+	return failure
+}
+
+// Make an RPC call to a worker, waiting no longer than the deadline for it to return
+func callWorker(worker *worker, method string, req interface{}, res interface{}) error {
+	call := worker.Client.Go(method, req, res, make(chan *rpc.Call, 1))
+
+	// A nil channel is never ready, so there is no deadline when it is disabled
+	var deadline <-chan time.Time
+	if callDeadline > 0 {
+		timer := time.NewTimer(callDeadline)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-deadline:
+		return errDeadline
+	}
+}
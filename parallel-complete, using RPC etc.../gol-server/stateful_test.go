@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestStatefulDeadline runs a stateful game on workers which always miss the deadline
+// Every turn must be calculated on the server without sending halos to the slow workers, which are kept
+func TestStatefulDeadline(t *testing.T) {
+	const height, width = 40, 30
+	slow := []*FakeWorker{{delay: time.Second}, {delay: time.Second}}
+	_, disconnect := connectFakeWorkers(t, 20*time.Millisecond, slow...)
+	defer disconnect()
+
+	board := randomBoard(height, width, 1)
+	for _, topology := range []stubs.Topology{stubs.Torus, stubs.KleinBottle} {
+		game := &session{ID: "deadline", height: height, width: width, threads: 2, rule: stubs.ConwayRule, topology: topology, board: board}
+		runner := newStatefulRunner(game)
+		expected := board
+		for turn := 1; turn <= 3; turn++ {
+			runner.Step(0)
+			expected = expectedTurn(expected, height, width, stubs.ConwayRule, topology)
+			if runner.Turn() != turn {
+				t.Fatalf("%v: expected turn %d, got %d", topology, turn, runner.Turn())
+			}
+			if !sameBoard(runner.Board(), expected) {
+				t.Fatalf("%v: board differs on turn %d", topology, turn)
+			}
+		}
+		runner.Close()
+	}
+
+	for i, fake := range slow {
+		if fake.Calls("StripTurn") == 0 {
+			t.Errorf("Expected worker %d to be asked for strip turns", i)
+		}
+		if fake.Calls("DoTurn") != 0 {
+			t.Errorf("Expected turns after a missed deadline to be calculated on the server, worker %d was sent %d halos", i, fake.Calls("DoTurn"))
+		}
+	}
+	if connectedWorkers() != len(slow) {
+		t.Errorf("Expected slow workers to be kept, %d of %d are connected", connectedWorkers(), len(slow))
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file sends visual updates to the controller
+// Boards are sent from their own goroutine, so a slow controller doesn't hold up the game
+// With a frame rate, turns which come up while the last board is still being sent are skipped
+// Most turns only send the cells which flipped, with the whole board sent every keyframeInterval reports
+// The controller can then never be far out of step, even if it started from the wrong board
+
+// keyframeInterval is the number of reports from one whole board being sent to the next
+const keyframeInterval = 100
+
+// visualState remembers the board last sent to the controller
+type visualState struct {
+	shown         [][]bool
+	sinceKeyframe int
+	height, width int
+}
+
+func newVisualState(height, width int) *visualState {
+	return &visualState{height: height, width: width}
+}
+
+// Make the report for a turn, with a delta if one is smaller than the board
+func (v *visualState) report(turn int, board [][]bool) stubs.BoardStateReport {
+	report := stubs.BoardStateReport{CompletedTurns: turn}
+	if v.shown != nil && v.sinceKeyframe < keyframeInterval-1 {
+		delta := stubs.NewDelta(v.shown, board, v.height, v.width)
+		// A bitboard takes at most a bit per cell, so a delta bigger than that isn't worth sending
+		if len(delta.Gaps) < v.height*v.width/8 {
+			report.Delta = delta
+		}
+	}
+
+	if report.Delta == nil {
+		report.Board = stubs.BitBoardFromSlice(board, v.height, v.width)
+		v.sinceKeyframe = 0
+	} else {
+		v.sinceKeyframe++
+	}
+
+	if v.shown == nil {
+		v.shown = make([][]bool, v.height)
+		for row := range v.shown {
+			v.shown[row] = make([]bool, v.width)
+		}
+	}
+	for row := range v.shown {
+		copy(v.shown[row], board[row])
+	}
+	return report
+}
+
+// visualFrame is a copy of the board on a turn, waiting to be sent
+type visualFrame struct {
+	turn  int
+	board [][]bool
+}
+
+// visualPublisher sends the boards offered by the game loop to the controller
+// Only one board is sent at a time, ready holds a token while the publisher is free for the next one
+type visualPublisher struct {
+	controller *rpc.Client
+	state      *visualState
+	interval   time.Duration
+	lastOffer  time.Time
+	lastTurn   int
+
+	frames   chan visualFrame
+	ready    chan bool
+	done     chan bool
+	stopOnce sync.Once
+	// failed is ready once a board couldn't be sent, as the controller has probably gone
+	failed chan bool
+}
+
+// Start a publisher sending at most frameRate boards a second, or every board if frameRate is zero
+func newVisualPublisher(controller *rpc.Client, height, width, frameRate int) *visualPublisher {
+	p := &visualPublisher{
+		controller: controller,
+		state:      newVisualState(height, width),
+		frames:     make(chan visualFrame, 1),
+		ready:      make(chan bool, 1),
+		done:       make(chan bool),
+		failed:     make(chan bool, 1),
+		lastTurn:   -1,
+	}
+	if frameRate > 0 {
+		p.interval = time.Second / time.Duration(frameRate)
+	}
+	p.ready <- true
+	go p.run()
+	return p
+}
+
+// Offer the board on a turn to be sent
+// With a frame rate it is dropped if a frame isn't due yet or the last one is still being sent,
+// otherwise we wait for the last one to be sent
+// board is only called if the board will be sent, as getting it can be slow
+func (p *visualPublisher) offer(turn int, board func() [][]bool) {
+	if p.interval > 0 {
+		if time.Since(p.lastOffer) < p.interval {
+			return
+		}
+		select {
+		case <-p.ready:
+		default:
+			return
+		}
+	} else {
+		<-p.ready
+	}
+	p.send(turn, board())
+}
+
+// Flush sends the board on a turn if it was skipped, ignoring the frame rate
+// If wait is set the controller has the board on the turn when this returns,
+// otherwise the board isn't sent if the last one is still being sent
+func (p *visualPublisher) flush(turn int, board func() [][]bool, wait bool) {
+	if wait {
+		<-p.ready
+	} else {
+		select {
+		case <-p.ready:
+		default:
+			return
+		}
+	}
+	if turn != p.lastTurn {
+		p.send(turn, board())
+		if !wait {
+			return
+		}
+		<-p.ready
+	}
+	p.ready <- true
+}
+
+// Finish sends the board on the last turn if it was skipped, then stops the publisher
+// No visual updates arrive after this returns, so the controller can be told the game has ended
+func (p *visualPublisher) finish(turn int, board func() [][]bool) {
+	p.stopOnce.Do(func() {
+		p.flush(turn, board, true)
+		close(p.frames)
+		<-p.done
+	})
+}
+
+// Stop waits for the board being sent, if there is one, without sending any more
+func (p *visualPublisher) stop() {
+	p.stopOnce.Do(func() {
+		close(p.frames)
+		<-p.done
+	})
+}
+
+// Send a copy of the board, as the runner may change its board once we return
+// There must be a token from ready
+func (p *visualPublisher) send(turn int, cells [][]bool) {
+	p.lastOffer = time.Now()
+	p.lastTurn = turn
+	p.frames <- visualFrame{turn: turn, board: copyBoard(cells)}
+}
+
+func copyBoard(board [][]bool) [][]bool {
+	cells := make([][]bool, len(board))
+	for row := range board {
+		cells[row] = make([]bool, len(board[row]))
+		copy(cells[row], board[row])
+	}
+	return cells
+}
+
+func (p *visualPublisher) run() {
+	lastTurn := -1
+	for frame := range p.frames {
+		report := p.state.report(frame.turn, frame.board)
+		if lastTurn >= 0 && frame.turn > lastTurn+1 {
+			report.SkippedTurns = frame.turn - lastTurn - 1
+		}
+		lastTurn = frame.turn
+		err := p.controller.Call(stubs.ControllerTurnComplete, report, &stubs.Empty{})
+		if err != nil {
+			select {
+			case p.failed <- true:
+			default:
+			}
+		}
+		p.ready <- true
+	}
+	close(p.done)
+}
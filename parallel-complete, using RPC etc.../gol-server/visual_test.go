@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestVisualKeyframes checks most reports only have the flipped cells, with the whole board every keyframeInterval reports,
+// and that a controller following the reports always has the right board
+func TestVisualKeyframes(t *testing.T) {
+	const height, width = 100, 100
+	board := make([][]bool, height)
+	shown := make([][]bool, height)
+	for row := range board {
+		board[row] = make([]bool, width)
+		shown[row] = make([]bool, width)
+	}
+
+	state := newVisualState(height, width)
+	for turn := 0; turn <= 2*keyframeInterval; turn++ {
+		if turn > 0 {
+			cell := (turn * 37) % (height * width)
+			board[cell/width][cell%width] = !board[cell/width][cell%width]
+		}
+		report := state.report(turn, board)
+
+		keyframe := turn%keyframeInterval == 0
+		if keyframe && (report.Board == nil || report.Delta != nil) {
+			t.Fatalf("Expected the whole board on report %d", turn)
+		}
+		if !keyframe && (report.Board != nil || report.Delta == nil) {
+			t.Fatalf("Expected only the flipped cells on report %d", turn)
+		}
+
+		if report.Board != nil {
+			shown = report.Board.ToSlice()
+		} else if err := report.Delta.Apply(shown); err != nil {
+			t.Fatal(err)
+		}
+		for row := range board {
+			for col := range board[row] {
+				if shown[row][col] != board[row][col] {
+					t.Fatalf("Cell (%d, %d) is wrong after report %d", col, row, turn)
+				}
+			}
+		}
+	}
+}
+
+// TestVisualLargeChange checks the whole board is sent when so many cells flip that it is smaller than the delta
+func TestVisualLargeChange(t *testing.T) {
+	const height, width = 16, 16
+	board := make([][]bool, height)
+	for row := range board {
+		board[row] = make([]bool, width)
+	}
+	state := newVisualState(height, width)
+	state.report(0, board)
+
+	for row := range board {
+		for col := range board[row] {
+			board[row][col] = true
+		}
+	}
+	report := state.report(1, board)
+	if report.Board == nil || report.Delta != nil {
+		t.Error("Expected the whole board when every cell flips")
+	}
+}
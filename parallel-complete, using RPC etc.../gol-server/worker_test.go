@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math/rand"
+	"net"
+	"net/rpc"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/engine"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file has a worker which runs in the test, connected to the server over a pipe instead of the network
+
+// FakeWorker calculates halos like a real worker, and holds strips without swapping edges,
+// so it can only take part in stateful games which it makes miss the deadline
+// Calls to DoTurn and StripTurn wait for delay, and DoTurn returns an error if fail is set
+type FakeWorker struct {
+	delay time.Duration
+	fail  bool
+
+	mutex   sync.Mutex
+	calls   map[string]int
+	rows    int
+	strips  map[string]stubs.Fragment
+	turns   map[string]int
+	release chan struct{}
+}
+
+func (f *FakeWorker) record(method string, rows int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls[method]++
+	f.rows += rows
+}
+
+// Wait for the delay, or until the test finishes
+func (f *FakeWorker) wait() {
+	select {
+	case <-time.After(f.delay):
+	case <-f.release:
+	}
+}
+
+func (f *FakeWorker) DoTurn(req stubs.DoTurnRequest, res *stubs.DoTurnResponse) error {
+	f.record("DoTurn", req.Halo.EndPtr-req.Halo.StartPtr)
+	f.wait()
+	if f.fail {
+		return rpc.ServerError("worker failed")
+	}
+	res.Frag = engine.DoTurn(req.Halo, 1, req.Rule, req.Topology)
+	return nil
+}
+
+func (f *FakeWorker) LoadStrip(req stubs.LoadStripRequest, res *stubs.Empty) error {
+	f.record("LoadStrip", req.Strip.EndRow-req.Strip.StartRow)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.strips[req.GameID] = req.Strip
+	f.turns[req.GameID] = req.Turn
+	return nil
+}
+
+func (f *FakeWorker) StripTurn(req stubs.StripTurnRequest, res *stubs.Empty) error {
+	f.record("StripTurn", 0)
+	f.wait()
+	return rpc.ServerError("the fake worker can't swap edges")
+}
+
+func (f *FakeWorker) GetStrip(req stubs.StripRequest, res *stubs.StripResponse) error {
+	f.record("GetStrip", 0)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	res.Strip, res.Turn = f.strips[req.GameID], f.turns[req.GameID]
+	return nil
+}
+
+func (f *FakeWorker) DropStrip(req stubs.StripRequest, res *stubs.Empty) error {
+	f.record("DropStrip", 0)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.strips, req.GameID)
+	return nil
+}
+
+// Calls returns how many times a method was called
+func (f *FakeWorker) Calls(method string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls[method]
+}
+
+// Rows returns the number of rows the worker has been given
+func (f *FakeWorker) Rows() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.rows
+}
+
+// Connect fake workers to the server in place of any real ones, until the returned function is called
+// The deadline is set for the test too, zero waits forever
+func connectFakeWorkers(t *testing.T, deadline time.Duration, fakes ...*FakeWorker) ([]*worker, func()) {
+	release := make(chan struct{})
+	connected := make([]*worker, len(fakes))
+	for i, fake := range fakes {
+		fake.calls = make(map[string]int)
+		fake.strips = make(map[string]stubs.Fragment)
+		fake.turns = make(map[string]int)
+		fake.release = release
+
+		server := rpc.NewServer()
+		err := server.RegisterName("Worker", fake)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverEnd, clientEnd := net.Pipe()
+		go server.ServeConn(serverEnd)
+		connected[i] = &worker{Client: rpc.NewClient(clientEnd), Address: "fake:" + strconv.Itoa(i)}
+	}
+
+	workersMutex.Lock()
+	previous := workers
+	workers = append([]*worker(nil), connected...)
+	workersMutex.Unlock()
+	previousDeadline := callDeadline
+	callDeadline = deadline
+
+	return connected, func() {
+		close(release)
+		callDeadline = previousDeadline
+		workersMutex.Lock()
+		workers = previous
+		workersMutex.Unlock()
+		for _, w := range connected {
+			w.Client.Close()
+		}
+	}
+}
+
+// connectedWorkers returns how many workers the server still has
+func connectedWorkers() int {
+	workersMutex.Lock()
+	defer workersMutex.Unlock()
+	return len(workers)
+}
+
+// randomBoard makes a board where about a third of the cells are alive
+func randomBoard(height, width int, seed int64) [][]bool {
+	random := rand.New(rand.NewSource(seed))
+	board := make([][]bool, height)
+	for row := range board {
+		board[row] = make([]bool, width)
+		for col := range board[row] {
+			board[row][col] = random.Intn(3) == 0
+		}
+	}
+	return board
+}
+
+// expectedTurn calculates the next turn of a whole board without any workers
+func expectedTurn(board [][]bool, height, width int, rule stubs.Rule, topology stubs.Topology) [][]bool {
+	next := make([][]bool, height)
+	for row := range next {
+		next[row] = make([]bool, width)
+	}
+	copyFragment(next, engine.DoTurn(makeHalo(0, height, height, width, board, topology), 1, rule, topology))
+	return next
+}
+
+func sameBoard(a, b [][]bool) bool {
+	for row := range a {
+		for col := range a[row] {
+			if a[row][col] != b[row][col] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"net/rpc"
+	"strconv"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/engine"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file contains the RPC functions for stateful games,
+// where we keep a strip of the board and swap edge rows with the workers next to us
+
+// strip is the part of a stateful game's board kept by this worker
+type strip struct {
+	mutex sync.Mutex
+
+	rows     [][]bool
+	startRow int
+	endRow   int
+	turn     int
+
+	// The edges of the strip on the previous turn, in case a neighbour is a turn behind us
+	prevTop    []bool
+	prevBottom []bool
+
+	threads  int
+	rule     stubs.Rule
+	topology stubs.Topology
+	up       stubs.EdgeSource
+	down     stubs.EdgeSource
+}
+
+// Global variables for stateful games
+var (
+	strips      = make(map[string]*strip)
+	stripsMutex sync.Mutex
+
+	// Connections to the other workers we swap edges with
+	peers      = make(map[string]*rpc.Client)
+	peersMutex sync.Mutex
+)
+
+// LoadStrip is called by the server to give us a strip of a stateful game to keep
+func (w *Worker) LoadStrip(req stubs.LoadStripRequest, res *stubs.Empty) (err error) {
+	println("Loading rows", req.Strip.StartRow, "to", req.Strip.EndRow, "of game", req.GameID, "at turn", req.Turn)
+	newStrip := &strip{
+		rows:     req.Strip.BitBoard.ToSlice(),
+		startRow: req.Strip.StartRow,
+		endRow:   req.Strip.EndRow,
+		turn:     req.Turn,
+
+		threads:  req.Threads,
+		rule:     req.Rule,
+		topology: req.Topology,
+		up:       req.Up,
+		down:     req.Down,
+	}
+
+	stripsMutex.Lock()
+	strips[req.GameID] = newStrip
+	stripsMutex.Unlock()
+	return
+}
+
+// DropStrip is called by the server when a stateful game has finished
+func (w *Worker) DropStrip(req stubs.StripRequest, res *stubs.Empty) (err error) {
+	stripsMutex.Lock()
+	delete(strips, req.GameID)
+	stripsMutex.Unlock()
+	return
+}
+
+// GetStrip is called by the server when it needs the cells of our strip
+func (w *Worker) GetStrip(req stubs.StripRequest, res *stubs.StripResponse) (err error) {
+	s, err := findStrip(req.GameID)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res.Turn = s.turn
+	res.Strip = stubs.Fragment{
+		StartRow: s.startRow,
+		EndRow:   s.endRow,
+		BitBoard: stubs.BitBoardFromSlice(s.rows, len(s.rows), len(s.rows[0])),
+	}
+	return
+}
+
+// GetEdge is called by other workers to get the top or bottom row of our strip on a turn
+func (w *Worker) GetEdge(req stubs.EdgeRequest, res *stubs.EdgeResponse) (err error) {
+	s, err := findStrip(req.GameID)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var row []bool
+	switch {
+	case req.Turn == s.turn && req.Bottom:
+		row = s.rows[len(s.rows)-1]
+	case req.Turn == s.turn:
+		row = s.rows[0]
+	// We may have already finished the turn the other worker is still calculating
+	case req.Turn == s.turn-1 && s.prevBottom != nil && req.Bottom:
+		row = s.prevBottom
+	case req.Turn == s.turn-1 && s.prevTop != nil:
+		row = s.prevTop
+	default:
+		return errors.New("edge for turn " + strconv.Itoa(req.Turn) + " requested, but strip is at turn " + strconv.Itoa(s.turn))
+	}
+	res.Row = stubs.BitBoardFromSlice([][]bool{row}, 1, len(row))
+	return
+}
+
+// StripTurn is called by the server to calculate the next turn of our strip
+// We get the rows either side of our strip from our neighbours, then calculate the turn
+func (w *Worker) StripTurn(req stubs.StripTurnRequest, res *stubs.Empty) (err error) {
+	s, err := findStrip(req.GameID)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	if s.turn == req.Turn+1 {
+		// We have already done this turn, the server must be retrying
+		s.mutex.Unlock()
+		return
+	} else if s.turn != req.Turn {
+		s.mutex.Unlock()
+		return errors.New("turn " + strconv.Itoa(req.Turn) + " requested, but strip is at turn " + strconv.Itoa(s.turn))
+	}
+	rows := s.rows
+	s.mutex.Unlock()
+
+	// The mutex mustn't be held here, as our neighbour may be ourselves
+	width := len(rows[0])
+	up, err := fetchEdge(req.GameID, req.Turn, s.up, width)
+	if err != nil {
+		return err
+	}
+	down, err := fetchEdge(req.GameID, req.Turn, s.down, width)
+	if err != nil {
+		return err
+	}
+
+	cells := make([][]bool, 0, len(rows)+2)
+	cells = append(cells, up)
+	cells = append(cells, rows...)
+	cells = append(cells, down)
+	frag := engine.DoTurn(stubs.Halo{
+		BitBoard: stubs.BitBoardFromSlice(cells, len(cells), width),
+		Offset:   1,
+		StartPtr: s.startRow,
+		EndPtr:   s.endRow,
+	}, s.threads, s.rule, s.topology)
+
+	s.mutex.Lock()
+	s.prevTop = rows[0]
+	s.prevBottom = rows[len(rows)-1]
+	s.rows = frag.BitBoard.ToSlice()
+	s.turn++
+	s.mutex.Unlock()
+	return
+}
+
+// Find the strip we hold for a game
+func findStrip(gameID string) (*strip, error) {
+	stripsMutex.Lock()
+	defer stripsMutex.Unlock()
+	s, exists := strips[gameID]
+	if !exists {
+		return nil, errors.New("no strip for game " + gameID)
+	}
+	return s, nil
+}
+
+// Get the row next to one of our edges on a turn from the worker holding it
+func fetchEdge(gameID string, turn int, source stubs.EdgeSource, width int) ([]bool, error) {
+	if source.Dead {
+		return make([]bool, width), nil
+	}
+
+	peer, err := getPeer(source.Address)
+	if err != nil {
+		return nil, err
+	}
+	response := new(stubs.EdgeResponse)
+	err = peer.Call(stubs.WorkerGetEdge, stubs.EdgeRequest{GameID: gameID, Turn: turn, Bottom: source.Bottom}, response)
+	if err == rpc.ErrShutdown {
+		// Forget the broken connection so we dial again next time
+		peersMutex.Lock()
+		delete(peers, source.Address)
+		peersMutex.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	row := response.Row.ToSlice()[0]
+	if source.Mirror {
+		for l, r := 0, len(row)-1; l < r; l, r = l+1, r-1 {
+			row[l], row[r] = row[r], row[l]
+		}
+	}
+	return row, nil
+}
+
+// Get a connection to another worker, dialing it if we haven't already
+func getPeer(address string) (*rpc.Client, error) {
+	peersMutex.Lock()
+	defer peersMutex.Unlock()
+	if peer, exists := peers[address]; exists {
+		return peer, nil
+	}
+	peer, err := rpc.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	peers[address] = peer
+	return peer, nil
+}
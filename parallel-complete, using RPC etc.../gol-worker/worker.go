@@ -5,9 +5,9 @@ import (
 	"net"
 	"net/rpc"
 	"os"
-	"sync"
 	"time"
 
+	"uk.ac.bris.cs/gameoflife/engine"
 	"uk.ac.bris.cs/gameoflife/stubs"
 	"uk.ac.bris.cs/gameoflife/util"
 )
@@ -26,7 +26,7 @@ type Worker struct{}
 // It will pass the board and fragment pointers
 func (w *Worker) DoTurn(req stubs.DoTurnRequest, res *stubs.DoTurnResponse) (err error) {
 	
-	frag := doTurn(req.Halo, req.Threads)
+	frag := engine.DoTurn(req.Halo, req.Threads, req.Rule, req.Topology)
 	res.Frag = frag
 	return
 }
@@ -132,45 +132,3 @@ func connectToServer() bool {
 	println("Connected!")
 	return true
 }
-
-// GAME LOGIC BELOW
-
-// Calculate the next turn, given pointers to the start and end to operate over
-// Return a fragment of the board with the next turn's cells
-func doTurn(halo stubs.Halo, threads int) (boardFragment stubs.Fragment) {
-	width := halo.BitBoard.RowLength
-	board := halo.BitBoard.Bytes.Decode()
-	height := halo.EndPtr - halo.StartPtr
-	newBoard := make([][]bool, height)
-
-	
-	if threads > height {
-		threads = height
-	}
-	var wg sync.WaitGroup
-	
-	fragHeight := height / threads
-	for i := 0; i < threads; i++ {
-		
-		start := i * fragHeight
-		end := (i + 1) * fragHeight
-		if i == threads-1 {
-			end = height
-		}
-		
-		wg.Add(1)
-		
-		go updateRegion(start, end, halo, newBoard, width, board, &wg)
-	}
-
-	// Wait for all threads to finish
-	wg.Wait()
-
-	
-	boardFragment = stubs.Fragment{
-		StartRow: halo.StartPtr,
-		EndRow:   halo.EndPtr,
-		BitBoard: stubs.BitBoardFromSlice(newBoard, halo.EndPtr-halo.StartPtr, width), 
-	}
-	return boardFragment
-}
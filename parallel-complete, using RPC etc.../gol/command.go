@@ -0,0 +1,60 @@
+package gol
+
+import (
+	"net/rpc"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// This file turns the keys pressed into calls to the server
+// Digits pressed before a key are its count, e.g. 50n steps 50 turns, 100j runs to turn 100 and 10t limits the game to 10 turns a second
+
+// isCountKey returns whether a key is part of the count for the next key
+func isCountKey(key rune) bool {
+	return key >= '0' && key <= '9'
+}
+
+// keyCommands sends the keys pressed for a game to the server, remembering the count typed before a key
+type keyCommands struct {
+	server  *rpc.Client
+	gameID  string
+	count   int
+	counted bool
+}
+
+// Send a key to the server, or add it to the count if it is a digit
+// The count is used up by the next key, whether or not that key takes a count
+func (k *keyCommands) send(key rune) error {
+	if isCountKey(key) {
+		k.count = k.count*10 + int(key-'0')
+		k.counted = true
+		return nil
+	}
+	count, counted := k.count, k.counted
+	k.count, k.counted = 0, false
+
+	response := new(stubs.ServerResponse)
+	var err error
+	switch key {
+	case 'n':
+		err = k.server.Call(stubs.ServerStep, stubs.StepRequest{GameID: k.gameID, Turns: count}, response)
+	case 'j':
+		if !counted {
+			println("Type the turn to jump to before pressing j, e.g. 100j")
+			return nil
+		}
+		err = k.server.Call(stubs.ServerStep, stubs.StepRequest{GameID: k.gameID, Turns: count, ToTurn: true}, response)
+	case 't':
+		// Without a count the speed limit is removed
+		err = k.server.Call(stubs.ServerSetSpeed, stubs.SpeedRequest{GameID: k.gameID, TurnsPerSecond: count}, response)
+	default:
+		err = k.server.Call(stubs.ServerRegisterKeypress, stubs.KeypressRequest{GameID: k.gameID, Key: key}, response)
+	}
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		println("Server error:", response.Message)
+	}
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"net"
 	"net/rpc"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"uk.ac.bris.cs/gameoflife/stubs"
@@ -17,6 +20,7 @@ type controllerChannels struct {
 	ioCommand  chan<- ioCommand
 	ioIdle     <-chan bool
 	ioFilename chan<- string
+	ioHeader   chan fileHeader
 	ioInput    <-chan uint8
 	ioOutput   chan<- uint8
 	keypresses <-chan rune
@@ -34,10 +38,28 @@ type Controller struct {
 	lastAliveTurn int
 	lastAliveTime time.Time
 	stopChan      chan bool
+
+	// started is closed once the server has told us about the game, see start
+	// Calls from the server wait for it, as params aren't known until then when resuming or observing
+	started chan bool
+}
+
+// Start is called once the server has started the game, or we are watching it
+// The rule and topology are always taken from the server, as a resumed game keeps its own, so saved patterns have the right rule
+// If we weren't given the size of the board it is taken from the server too, and sent in a GameStarted event
+func (c *Controller) start(turn, height, width int, rule stubs.Rule, topology stubs.Topology) {
+	c.params.Rule, c.params.RuleSet = rule, true
+	c.params.Topology = topology
+	if c.params.ImageWidth == 0 || c.params.ImageHeight == 0 {
+		c.params.ImageHeight, c.params.ImageWidth = height, width
+		c.channels.events <- GameStarted{CompletedTurns: turn, Width: width, Height: height}
+	}
+	close(c.started)
 }
 
 // GameStateChange is called by the server to report a change in game state
 func (c *Controller) GameStateChange(req stubs.StateChangeReport, res *stubs.Empty) (err error) {
+	<-c.started
 	println("Received state change report")
 	println(req.Previous.String(), "->", req.New.String())
 	c.channels.events <- StateChange{
@@ -54,22 +76,48 @@ func (c *Controller) GameStateChange(req stubs.StateChangeReport, res *stubs.Emp
 // FinalTurnComplete is called by the server when it has processed all turns
 // It will send the final board which can then be saved
 func (c *Controller) FinalTurnComplete(req stubs.BoardStateReport, res *stubs.Empty) (err error) {
+	<-c.started
 	println("Final turn complete")
 	c.channels.events <- FinalTurnComplete{
 		CompletedTurns: req.CompletedTurns,
 		Alive:          util.GetAliveCells(req.Board.ToSlice()),
 	}
 
-	go saveBoard(req.Board.ToSlice(), req.CompletedTurns, c.params, c.channels)
+	// Observers leave saving to the controller running the game
+	if !c.params.Observe {
+		go saveBoard(req.Board.ToSlice(), req.Ages, req.CompletedTurns, c.params, c.channels)
+	}
 	c.stopChan <- true
 	return
 }
 
 // TurnComplete is called by the server when a turn has been completed
-// It contains a copy of the board on this turn so we can display it
+// It contains a copy of the board on this turn, or the cells which flipped since the last one, so we can display it
 func (c *Controller) TurnComplete(req stubs.BoardStateReport, res *stubs.Empty) (err error) {
+	<-c.started
 	c.timeoutTimer.Reset(5 * time.Second)
 
+	if req.Delta != nil {
+		// The server always sends a whole board first, so this shouldn't happen
+		if c.previous == nil {
+			println("Received cells flipped on turn", req.CompletedTurns, "before the board")
+			return
+		}
+		// Flip the cells on our copy of the board as we send the events, so they always match
+		err = req.Delta.ForEach(func(row, col int) {
+			c.previous[row][col] = !c.previous[row][col]
+			c.channels.events <- CellFlipped{
+				CompletedTurns: req.CompletedTurns,
+				Cell:           util.Cell{X: col, Y: row},
+			}
+		})
+		if err != nil {
+			println("Error reading cells flipped on turn", req.CompletedTurns, err.Error())
+		}
+		c.channels.events <- TurnComplete{CompletedTurns: req.CompletedTurns, SkippedTurns: req.SkippedTurns}
+		return
+	}
+
 	// If any cells have changed then send a cellflipped event
 	board := req.Board.ToSlice()
 	for row := 0; row < req.Board.NumRows; row++ {
@@ -89,22 +137,24 @@ func (c *Controller) TurnComplete(req stubs.BoardStateReport, res *stubs.Empty)
 			}
 		}
 	}
-	c.channels.events <- TurnComplete{req.CompletedTurns}
+	c.channels.events <- TurnComplete{CompletedTurns: req.CompletedTurns, SkippedTurns: req.SkippedTurns}
 	c.previous = board
 	return
 }
 
 // SaveBoard is called by the server when it wants us to save the board (e.g. if we send an 's' key)
 func (c *Controller) SaveBoard(req stubs.BoardStateReport, res *stubs.Empty) (err error) {
+	<-c.started
 	println("Received save board request")
 	// Save the board
-	go saveBoard(req.Board.ToSlice(), req.CompletedTurns, c.params, c.channels)
+	go saveBoard(req.Board.ToSlice(), req.Ages, req.CompletedTurns, c.params, c.channels)
 	return
 }
 
 // ReportAliveCells is called by the server to report how many cells are alive
 // This is usually called at regular intervals
 func (c *Controller) ReportAliveCells(req stubs.AliveCellsReport, res *stubs.Empty) (err error) {
+	<-c.started
 	c.timeoutTimer.Reset(5 * time.Second)
 
 	println("Received alive cells report")
@@ -112,13 +162,15 @@ func (c *Controller) ReportAliveCells(req stubs.AliveCellsReport, res *stubs.Emp
 	now := time.Now()
 	turnsDiff := req.CompletedTurns - c.lastAliveTurn
 	timeDiff := now.Sub(c.lastAliveTime)
-	fmt.Printf("%.2f", float64(turnsDiff)/timeDiff.Seconds())
-	println(" turns/s")
+	fmt.Fprintf(os.Stderr, "%.2f turns/s\n", float64(turnsDiff)/timeDiff.Seconds())
 
 	c.lastAliveTime = now
 	c.lastAliveTurn = req.CompletedTurns
 
 	c.channels.events <- AliveCellsCount{CompletedTurns: req.CompletedTurns, CellsCount: req.NumAlive}
+	if req.TotalTiles > 0 {
+		c.channels.events <- ActiveTilesCount{CompletedTurns: req.CompletedTurns, ActiveTiles: req.ActiveTiles, TotalTiles: req.TotalTiles}
+	}
 	return
 }
 
@@ -126,17 +178,38 @@ func (c *Controller) ReportAliveCells(req stubs.AliveCellsReport, res *stubs.Emp
 // It will also start an RPC server and only returns when this is closed
 // When this function ends, it will cleanly close the events channel, signaling the program to halt
 func controller(p Params, c controllerChannels) {
-	board := make([][]bool, p.ImageHeight)
-	for row := 0; row < p.ImageHeight; row++ {
-		board[row] = make([]bool, p.ImageWidth)
-	}
-
-	if p.ResumeGame {
+	var board [][]bool
+	if p.ResumeGame || p.Observe {
+		// The size of the board is given by the server, see Controller.start
 		println("Resuming game from the server")
+		board = make([][]bool, p.ImageHeight)
+		for row := 0; row < p.ImageHeight; row++ {
+			board[row] = make([]bool, p.ImageWidth)
+		}
 	} else {
 		println("Starting new game")
 
-		loadBoard(c, p, board)
+		// The board is the size given in the file if we weren't given one
+		// A rule we were given takes priority over one in the file
+		var header fileHeader
+		board, header = loadBoard(c, p)
+		if header.err != nil {
+			// The io goroutine has already sent an IOError, so just stop cleanly
+			println("Error loading board:", header.err.Error())
+			c.events <- StateChange{CompletedTurns: 0, NewState: stubs.Quitting}
+			c.ioCommand <- ioCheckIdle
+			<-c.ioIdle
+			close(c.events)
+			return
+		}
+		p.ImageWidth, p.ImageHeight = header.width, header.height
+		if !p.RuleSet && header.rule != nil {
+			p.Rule, p.RuleSet = *header.rule, true
+		}
+	}
+	// A resumed or observed game's rule is replaced by the one from the server, see Controller.start
+	if !p.RuleSet {
+		p.Rule, p.RuleSet = stubs.ConwayRule, true
 	}
 
 	// Create a RPC server for ourselves
@@ -151,6 +224,7 @@ func controller(p Params, c controllerChannels) {
 		lastAliveTime: time.Now(),
 
 		stopChan: make(chan bool),
+		started:  make(chan bool),
 	}
 	controllerRPC := rpc.NewServer()
 	controllerRPC.Register(&controller)
@@ -163,7 +237,7 @@ func controller(p Params, c controllerChannels) {
 	}
 
 	// Start a goroutine to connect to the server and start a game
-	go runGame(p, c, board, controller, listener)
+	go runGame(p, c, board, &controller, listener)
 
 	controllerRPC.Accept(listener)
 
@@ -175,7 +249,7 @@ func controller(p Params, c controllerChannels) {
 
 // RunGame is responsible for connecting to the server and handling channels from the server
 // It will attempt to establish a connection, if this is successful it will then call ServerStartGame
-func runGame(p Params, c controllerChannels, board [][]bool, controller Controller, listener net.Listener) {
+func runGame(p Params, c controllerChannels, board [][]bool, controller *Controller, listener net.Listener) {
 	defer listener.Close()
 	server, err := rpc.Dial("tcp", p.ServerAddress)
 	defer server.Close()
@@ -186,8 +260,12 @@ func runGame(p Params, c controllerChannels, board [][]bool, controller Controll
 	}
 
 	println("Established connection with the server: ", p.ServerAddress)
+	if p.Observe {
+		observeGame(p, c, server, controller)
+		return
+	}
 	// This contains the response of the StartGame RPC call
-	response := new(stubs.ServerResponse)
+	response := new(stubs.StartGameResponse)
 
 	// Attempt to start a game with the server
 	// We allow for 4 retries incase the server is slow at closing a previous connection
@@ -202,17 +280,29 @@ func runGame(p Params, c controllerChannels, board [][]bool, controller Controll
 		// Pass all the information required to start (or continue) a game
 		err = server.Call(stubs.ServerStartGame, stubs.StartGameRequest{
 			ControllerAddress: p.OurIP + ":" + p.Port,
+			GameID:            p.GameID,
 			Height:            p.ImageHeight,
 			Width:             p.ImageWidth,
 			MaxTurns:          p.Turns,
 			Threads:           p.Threads,
 			Board:             stubs.BitBoardFromSlice(board, p.ImageHeight, p.ImageWidth),
 			VisualUpdates:     p.VisualUpdates,
+			FrameRate:         p.FrameRate,
+			OnDisconnect:      p.OnDisconnect,
+			Rule:              p.Rule,
+			RuleSet:           p.RuleSet,
+			Topology:          p.Topology,
+			Engine:            p.Engine,
+			TrackAges:         p.OutputFormat == PNG,
 			StartNew:          !p.ResumeGame,
 		}, response)
 
 		if err == nil && response.Success {
-			println("Game starting!")
+			println("Game starting! Game ID:", response.GameID)
+			if p.ResumeGame {
+				println("Resuming from turn", response.Turn)
+			}
+			controller.start(response.Turn, response.Height, response.Width, response.Rule, response.Topology)
 			break
 		}
 
@@ -225,16 +315,20 @@ func runGame(p Params, c controllerChannels, board [][]bool, controller Controll
 	}
 
 	// Handle all keypresses and channel inputs until the game stops
+	keys := &keyCommands{server: server, gameID: response.GameID}
 	for {
 		select {
 		case key := <-c.keypresses:
-			err = server.Call(stubs.ServerRegisterKeypress, stubs.KeypressRequest{Key: key}, response)
+			err = keys.send(key)
 			if err != nil {
 				println("Error sending keypress to server:", err.Error())
 			}
 		case <-controller.timeoutTimer.C:
-			println("Timed out waiting for an AliveCellCount")
-			return
+			// Reports stop while the game is paused, so only give up if the server has gone
+			if !serverAlive(server) {
+				return
+			}
+			controller.timeoutTimer.Reset(5 * time.Second)
 		case <-controller.stopChan:
 			println("Received stop signal")
 			println("Closing RPC server")
@@ -244,28 +338,78 @@ func runGame(p Params, c controllerChannels, board [][]bool, controller Controll
 
 }
 
+// Check the server can still be reached when we haven't heard from it for a while
+func serverAlive(server *rpc.Client) bool {
+	err := server.Call(stubs.ServerPing, stubs.Empty{}, &stubs.Empty{})
+	if err != nil {
+		println("Lost connection to the server:", err.Error())
+		return false
+	}
+	return true
+}
+
 // Load a board slice from a file
 // This will properly prepare all the channels for reading
-func loadBoard(c controllerChannels, p Params, board [][]bool) {
-	filename := strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight)
+// Returns the board and the header of the file, which has the size of the board and the file's rule
+// If the file couldn't be read the header's err is set and there is no board
+func loadBoard(c controllerChannels, p Params) ([][]bool, fileHeader) {
+	filename := p.InputFile
+	if filename == "" {
+		filename = "images/" + strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight) + ".pgm"
+	}
 	println("Reading in file", filename)
 
 	c.ioCommand <- ioInput
 	c.ioFilename <- filename
+	header := <-c.ioHeader
+	if header.err != nil {
+		return nil, header
+	}
 
-	boardFromFileInput(board, p.ImageHeight, p.ImageWidth, c.ioInput, c.events)
+	board := make([][]bool, header.height)
+	for row := 0; row < header.height; row++ {
+		board[row] = make([]bool, header.width)
+	}
+	boardFromFileInput(board, header.height, header.width, c.ioInput, c.events)
+	return board, header
 }
 
 // Save a board slice to the file
 // This will properly prepare all the channels for writing
-func saveBoard(board [][]bool, completedTurns int, p Params, c controllerChannels) {
-	filename := strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight) + "x" + strconv.Itoa(completedTurns)
+// ages are the ages of the cells if the server tracked them, otherwise nil
+func saveBoard(board [][]bool, ages [][]int16, completedTurns int, p Params, c controllerChannels) {
+	height := len(board)
+	width := 0
+	if height > 0 {
+		width = len(board[0])
+	}
+	filename := outputFilename(p, width, height, completedTurns)
 	println("Saving to file", filename)
 
 	c.ioCommand <- ioOutput
 	c.ioFilename <- filename
+	c.ioHeader <- fileHeader{width: width, height: height, rule: &p.Rule, turn: completedTurns, ages: ages}
 
-	boardToFileOutput(board, p.ImageHeight, p.ImageWidth, c.ioOutput)
+	boardToFileOutput(board, height, width, c.ioOutput)
+}
+
+// Fill in the output template to get the name a board is saved as
+func outputFilename(p Params, width, height, completedTurns int) string {
+	template := p.OutputTemplate
+	if template == "" {
+		template = DefaultOutputTemplate
+	}
+
+	name := strconv.Itoa(width) + "x" + strconv.Itoa(height)
+	if p.InputFile != "" {
+		name = strings.TrimSuffix(filepath.Base(p.InputFile), filepath.Ext(p.InputFile))
+	}
+	return strings.NewReplacer(
+		"{name}", name,
+		"{w}", strconv.Itoa(width),
+		"{h}", strconv.Itoa(height),
+		"{turn}", strconv.Itoa(completedTurns),
+	).Replace(template)
 }
 
 // Populate a board from a file input channel, sending events on cells set to alive
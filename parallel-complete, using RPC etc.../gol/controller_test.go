@@ -0,0 +1,116 @@
+package gol
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestUnreadableInput checks a game whose input file can't be read sends an IOError and then quits,
+// without trying to reach the server
+func TestUnreadableInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	invalid := filepath.Join(dir, "invalid.rle")
+	err = ioutil.WriteFile(invalid, []byte("#N no header\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+	}{
+		{"missing file", filepath.Join(dir, "missing.pgm")},
+		{"directory", dir},
+		{"invalid pattern", invalid},
+	}
+	for _, test := range tests {
+		events := make(chan Event, 10)
+		// Nothing is listening at the server address, so the test fails rather than hangs if the game is started
+		p := Params{Turns: 10, Threads: 1, InputFile: test.filename, Port: "0", ServerAddress: "localhost:1"}
+		go Run(p, events, nil)
+
+		var received []Event
+		timeout := time.After(5 * time.Second)
+	receive:
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					break receive
+				}
+				received = append(received, event)
+			case <-timeout:
+				t.Fatalf("%s: the events channel wasn't closed, got %v", test.name, received)
+			}
+		}
+
+		if len(received) != 2 {
+			t.Errorf("%s: expected an IOError and a StateChange, got %v", test.name, received)
+			continue
+		}
+		ioError, ok := received[0].(IOError)
+		if !ok || ioError.Filename != test.filename || ioError.Err == nil || ioError.CompletedTurns != 0 {
+			t.Errorf("%s: expected an IOError for %s, got %v", test.name, test.filename, received[0])
+		}
+		if received[1] != (StateChange{CompletedTurns: 0, NewState: stubs.Quitting}) {
+			t.Errorf("%s: expected a StateChange to Quitting, got %v", test.name, received[1])
+		}
+	}
+}
+
+// TestStartFromServer checks a resumed game takes its rule and topology from the server, so it is saved with the right rule,
+// and its size too if it wasn't given one
+func TestStartFromServer(t *testing.T) {
+	highLife, err := stubs.ParseRule("B36/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		width, height int
+		started       bool
+	}{
+		{"no size", 0, 0, true},
+		{"given size", 64, 32, false},
+	}
+	for _, test := range tests {
+		events := make(chan Event, 1)
+		c := Controller{
+			params:   Params{ImageWidth: test.width, ImageHeight: test.height, ResumeGame: true, Rule: stubs.ConwayRule, RuleSet: true, Topology: stubs.Torus},
+			channels: controllerChannels{events: events},
+			started:  make(chan bool),
+		}
+		c.start(30, 32, 64, highLife, stubs.KleinBottle)
+
+		if c.params.Rule != highLife || !c.params.RuleSet || c.params.Topology != stubs.KleinBottle {
+			t.Errorf("%s: expected the rule and topology from the server, got %v on a %v", test.name, c.params.Rule, c.params.Topology)
+		}
+		if c.params.ImageWidth != 64 || c.params.ImageHeight != 32 {
+			t.Errorf("%s: expected a 64x32 board, got %dx%d", test.name, c.params.ImageWidth, c.params.ImageHeight)
+		}
+		select {
+		case event := <-events:
+			if !test.started || event != (GameStarted{CompletedTurns: 30, Width: 64, Height: 32}) {
+				t.Errorf("%s: unexpected event %v", test.name, event)
+			}
+		default:
+			if test.started {
+				t.Errorf("%s: expected a GameStarted event", test.name)
+			}
+		}
+		select {
+		case <-c.started:
+		default:
+			t.Errorf("%s: calls from the server are still waiting after the game started", test.name)
+		}
+	}
+}
@@ -15,6 +15,14 @@ type Event interface {
 	GetCompletedTurns() int
 }
 
+// GameStarted is an Event notifying the user of the size of the board, when resuming or observing a game without giving it.
+// It is sent as soon as the server has started the game, or the observer is watching it, before any other Event.
+type GameStarted struct { // implements Event
+	CompletedTurns int
+	Width          int
+	Height         int
+}
+
 // AliveCellsCount is an Event notifying the user about the number of currently alive cells.
 // This Event should be sent every 2s.
 type AliveCellsCount struct { // implements Event
@@ -22,13 +30,30 @@ type AliveCellsCount struct { // implements Event
 	CellsCount     int
 }
 
+// ActiveTilesCount is an Event notifying the user about how much of the board the sparse engine is calculating.
+// This Event is sent after each AliveCellsCount when the game uses the sparse engine.
+type ActiveTilesCount struct { // implements Event
+	CompletedTurns int
+	ActiveTiles    int
+	TotalTiles     int
+}
+
 // ImageOutputComplete is an Event notifying the user about the completion of output.
 // This Event should be sent every time an image has been saved.
+// Filename is the path the file was saved to, like the Filename of an IOError for a file which couldn't be saved.
 type ImageOutputComplete struct { // implements Event
 	CompletedTurns int
 	Filename       string
 }
 
+// IOError is an Event notifying the user that an image or pattern file couldn't be read or written.
+// If the input file can't be read, the game doesn't start and a StateChange to Quitting is sent next.
+type IOError struct { // implements Event
+	CompletedTurns int
+	Filename       string
+	Err            error
+}
+
 // State represents a change in the state of execution.
 type State int
 
@@ -56,8 +81,10 @@ type CellFlipped struct { // implements Event
 // TurnComplete is an Event notifying the GUI about turn completion.
 // SDL will render a frame when this event is sent.
 // All CellFlipped events must be sent *before* TurnComplete.
+// SkippedTurns is the number of turns since the last TurnComplete which weren't shown, see Params.FrameRate.
 type TurnComplete struct { // implements Event
 	CompletedTurns int
+	SkippedTurns   int
 }
 
 // FinalTurnComplete is an Event notifying the testing framework about the new world state after execution finished.
@@ -91,6 +118,14 @@ func (event StateChange) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event GameStarted) String() string {
+	return fmt.Sprintf("Started %vx%v game", event.Width, event.Height)
+}
+
+func (event GameStarted) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event AliveCellsCount) String() string {
 	return fmt.Sprintf("Alive Cells %v", event.CellsCount)
 }
@@ -99,6 +134,14 @@ func (event AliveCellsCount) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event ActiveTilesCount) String() string {
+	return fmt.Sprintf("Active Tiles %v/%v", event.ActiveTiles, event.TotalTiles)
+}
+
+func (event ActiveTilesCount) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event ImageOutputComplete) String() string {
 	return fmt.Sprintf("File %v output complete", event.Filename)
 }
@@ -107,6 +150,14 @@ func (event ImageOutputComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event IOError) String() string {
+	return fmt.Sprintf("File %v error: %v", event.Filename, event.Err)
+}
+
+func (event IOError) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event CellFlipped) String() string {
 	return fmt.Sprintf("")
 }
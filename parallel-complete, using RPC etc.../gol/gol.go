@@ -1,5 +1,10 @@
 package gol
 
+import (
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
 // Params provides the details of how to run the Game of Life and which image to load.
 type Params struct {
 	Turns         int
@@ -11,8 +16,53 @@ type Params struct {
 	OurIP         string
 	VisualUpdates bool
 	ResumeGame    bool
+	GameID        string
+	Topology      stubs.Topology
+	Engine        stubs.Engine
+
+	// Rule is only used if RuleSet is true, otherwise the rule comes from the input file or is ConwayRule
+	// When resuming or observing, the game keeps its own rule and topology, which are taken from the server
+	// B/S (nothing is born or survives) is a rule too, so it can't be told apart by its value
+	Rule    stubs.Rule
+	RuleSet bool
+
+	// Observe watches the game GameID on the server instead of starting one, without being able to control it
+	// When resuming or observing, the width and height are taken from the server and sent in a GameStarted event
+	// if either of them is zero, otherwise they must match the game
+	Observe bool
+
+	// OnDisconnect is what the server does with the game if we disconnect without quitting
+	// Resuming the game takes it over from the turn it has reached, even if it is still running
+	OnDisconnect stubs.DisconnectPolicy
+
+	// FrameRate is the most boards the server sends a second for visual updates
+	// Turns in between are skipped so the game isn't held up, zero sends every turn
+	FrameRate int
+
+	// InputFile is a pattern or image file to load instead of images/<W>x<H>.pgm
+	// The width and height are taken from the file if they are zero
+	// Offset is where the top left of a pattern is placed on the board
+	// Crop lets a board given a different size to the input file drop any alive cells that don't fit,
+	// otherwise the sizes must match unless the file is placed at an offset, and no alive cells can be left out
+	// OutputFormat is the format the board is saved in
+	// OutputTemplate is the name the board is saved as, see DefaultOutputTemplate
+	// Threshold is the fraction of white a greyscale pixel must reach to be alive, zero means any non-black pixel
+	// Trail is the number of turns dead cells fade out over in PNG images, zero leaves no trail
+	InputFile      string
+	Offset         util.Cell
+	Crop           bool
+	OutputFormat   FileFormat
+	OutputTemplate string
+	Threshold      float64
+	Trail          int
 }
 
+// DefaultOutputTemplate is used when no output template is given
+// {name} is replaced by the name of the input file (or WxH), {w} and {h} by the size of the board
+// and {turn} by the turn being saved
+// Names without a directory are saved in out/
+const DefaultOutputTemplate = "{w}x{h}x{turn}"
+
 // Find the server address as an env variable
 func getServerAddressFromEnvs() string {
 	return "localhost:8020"
@@ -34,6 +84,7 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 	ioCommand := make(chan ioCommand)
 	ioIdle := make(chan bool)
 	ioFilename := make(chan string)
+	ioHeader := make(chan fileHeader)
 	ioImageInput := make(chan uint8)
 	ioImageOutput := make(chan uint8)
 
@@ -42,6 +93,7 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		ioCommand,
 		ioIdle,
 		ioFilename,
+		ioHeader,
 		ioImageInput,
 		ioImageOutput,
 		keyPresses,
@@ -51,7 +103,9 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 	ioChannels := ioChannels{
 		command:  ioCommand,
 		idle:     ioIdle,
+		events:   events,
 		filename: ioFilename,
+		header:   ioHeader,
 		output:   ioImageOutput,
 		input:    ioImageInput,
 	}
@@ -1,23 +1,41 @@
 package gol
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"strconv"
-	"strings"
+	"path/filepath"
+	"uk.ac.bris.cs/gameoflife/stubs"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
 type ioChannels struct {
 	command <-chan ioCommand
 	idle    chan<- bool
+	events  chan<- Event
 
 	filename <-chan string
+	header   chan fileHeader
 	output   <-chan uint8
 	input    chan<- uint8
 }
 
+// fileHeader is sent before the cells of a board
+// The io goroutine sends it for a file it has read, and receives it for a file it is writing
+// rule is the rule given in the file, or nil if it doesn't have one
+// turn is the turn of a board being written
+// ages are the ages of the cells of a board being written, if the server tracked them, see engine.Ages
+// err is set if a file couldn't be read, in which case no cells follow
+type fileHeader struct {
+	width  int
+	height int
+	rule   *stubs.Rule
+	turn   int
+	ages   [][]int16
+	err    error
+}
+
 // ioState is the internal ioState of the io goroutine.
 type ioState struct {
 	params   Params
@@ -38,91 +56,246 @@ const (
 	ioCheckIdle
 )
 
-// writePgmImage receives an array of bytes and writes it to a pgm file.
-func (io *ioState) writePgmImage(doWrite bool) {
-	_ = os.Mkdir("out", os.ModePerm)
-
-	// Request a filename from the distributor.
+// writeImage receives an array of bytes and writes it to a file in the output format.
+// The whole board is received before writing, so the distributor isn't left waiting if the file can't be written.
+func (io *ioState) writeImage() {
+	// Request a filename and the size of the board from the distributor.
 	filename := <-io.channels.filename
+	header := <-io.channels.header
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
-	util.Check(ioError)
-	defer file.Close()
-	if doWrite {
-		_, _ = file.WriteString("P5\n")
-		//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-		_, _ = file.WriteString(strconv.Itoa(io.params.ImageWidth))
-		_, _ = file.WriteString(" ")
-		_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
-		_, _ = file.WriteString("\n")
-		_, _ = file.WriteString(strconv.Itoa(255))
-		_, _ = file.WriteString("\n")
-	}
-	world := make([][]byte, io.params.ImageHeight)
-	for i := range world {
-		world[i] = make([]byte, io.params.ImageWidth)
-	}
-
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			val := <-io.channels.output
-			//if val != 0 {
-			//	fmt.Println(x, y)
-			//}
-			world[y][x] = val
+	world := make([][]byte, header.height)
+	for y := range world {
+		world[y] = make([]byte, header.width)
+		for x := range world[y] {
+			world[y][x] = <-io.channels.output
 		}
 	}
-	if doWrite {
-		for y := 0; y < io.params.ImageHeight; y++ {
-			for x := 0; x < io.params.ImageWidth; x++ {
-				_, ioError = file.Write([]byte{world[y][x]})
-				util.Check(ioError)
-			}
+
+	path, ioError := io.writeFile(filename, header, world)
+	if ioError != nil {
+		fmt.Fprintln(os.Stderr, "Error saving file", path+":", ioError)
+		io.channels.events <- IOError{CompletedTurns: header.turn, Filename: path, Err: ioError}
+		return
+	}
+
+	io.channels.events <- ImageOutputComplete{CompletedTurns: header.turn, Filename: path}
+	fmt.Fprintln(os.Stderr, "File", path, "output done!")
+}
+
+// writeFile writes a board to a file in the output format, returning the path of the file.
+func (io *ioState) writeFile(filename string, header fileHeader, world [][]byte) (string, error) {
+	format := io.params.OutputFormat
+	file, err := createOutputFile(filename, format.Extension())
+	if err != nil {
+		return filename + format.Extension(), err
+	}
+
+	switch format {
+	case PGM, PBM:
+		err = writePnmImage(file, format, world)
+	case PNG:
+		err = writePngImage(file, world, header.ages, io.params.Trail)
+	default:
+		err = writePattern(format, file, filepath.Base(filename), world, *header.rule)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return file.Name(), err
+}
+
+// writePnmImage writes a board to a pgm or pbm file.
+func writePnmImage(file *os.File, format FileFormat, world [][]byte) error {
+	magic := "P5"
+	if format == PBM {
+		magic = "P4"
+	}
+	width := 0
+	if len(world) > 0 {
+		width = len(world[0])
+	}
+
+	image, err := util.NewPNMWriter(file, magic, width, len(world))
+	if err != nil {
+		return err
+	}
+	for _, row := range world {
+		err = image.WriteRow(row)
+		if err != nil {
+			return err
 		}
 	}
-	ioError = file.Sync()
-	util.Check(ioError)
+	return image.Flush()
+}
 
-	fmt.Println("File", filename, "output done!")
+// createOutputFile creates the file a board is saved to.
+// Names without a directory are saved in out/, like the default WxHxT names.
+func createOutputFile(filename, extension string) (*os.File, error) {
+	path := filename + extension
+	if filepath.Dir(filename) == "." {
+		path = filepath.Join("out", path)
+	}
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(path)
 }
 
-// readPgmImage opens a pgm file and sends its data as an array of bytes.
-func (io *ioState) readPgmImage() {
+// readImage opens an image or pattern file and sends its data as an array of bytes.
+// The image is placed on the board at the offset, and the size of the board is sent first.
+func (io *ioState) readImage() {
 
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
 
-	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
-	util.Check(ioError)
+	image, rule, ioError := readInputFile(filename, io.params.Threshold)
+	header := fileHeader{rule: rule}
+	if ioError == nil {
+		header.width, header.height = boardSize(io.params, image)
+		// Patterns without any cells (e.g. an empty Life 1.06 file) have no size of their own
+		if header.width <= 0 || header.height <= 0 {
+			ioError = fmt.Errorf("the board would be %dx%d, give the board size for empty patterns", header.width, header.height)
+		} else if !io.params.Crop {
+			ioError = checkFit(io.params, image, header.width, header.height)
+		}
+	}
+	if ioError != nil {
+		// Tell the distributor there is no board coming
+		fmt.Fprintln(os.Stderr, "Error reading file", filename+":", ioError)
+		io.channels.events <- IOError{Filename: filename, Err: ioError}
+		io.channels.header <- fileHeader{err: ioError}
+		return
+	}
+	io.channels.header <- header
 
-	fields := strings.Fields(string(data))
+	if outside := cellsOutside(io.params, image, header.width, header.height); outside > 0 {
+		fmt.Fprintln(os.Stderr, outside, "alive cells of", filename, "are outside the board and were left out")
+	}
 
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
+	for y := 0; y < header.height; y++ {
+		imageY := y - io.params.Offset.Y
+		for x := 0; x < header.width; x++ {
+			imageX := x - io.params.Offset.X
+			if imageY < 0 || imageY >= len(image) || imageX < 0 || imageX >= len(image[imageY]) {
+				io.channels.input <- 0
+			} else {
+				io.channels.input <- image[imageY][imageX]
+			}
+		}
 	}
 
-	width, _ := strconv.Atoi(fields[1])
-	if width != io.params.ImageWidth {
-		panic("Incorrect width")
+	fmt.Fprintln(os.Stderr, "File", filename, "input done!")
+}
+
+// readInputFile reads an image or pattern file, working out the format from the file.
+// It returns the image and the rule given in the file, or nil if it doesn't have one.
+// Greyscale pixels are alive if they reach the threshold, see util.PNMReader.
+func readInputFile(filename string, threshold float64) ([][]byte, *stubs.Rule, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer file.Close()
 
-	height, _ := strconv.Atoi(fields[2])
-	if height != io.params.ImageHeight {
-		panic("Incorrect height")
+	// The start of the file is enough to tell which format it is in
+	reader := bufio.NewReader(file)
+	start, _ := reader.Peek(4096)
+	format := detectFileFormat(filename, start)
+	if format == PNG {
+		return nil, nil, errors.New("png images can only be written, not read")
+	}
+	if format == PGM || format == PBM {
+		image, err := util.NewPNMReader(reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		image.Threshold = threshold
+		pixels, err := image.ReadAll()
+		return pixels, nil, err
 	}
 
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
+	pattern, err := readPattern(format, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	image := make([][]byte, pattern.height)
+	for y := range image {
+		image[y] = make([]byte, pattern.width)
 	}
+	for _, cell := range pattern.cells {
+		image[cell.Y][cell.X] = 255
+	}
+	return image, pattern.rule, nil
+}
 
-	image := []byte(fields[4])
+// cellsOutside counts the alive cells of an image which are outside the board when it is placed at the offset.
+func cellsOutside(p Params, image [][]byte, width, height int) int {
+	outside := 0
+	for y := range image {
+		for x, b := range image[y] {
+			boardX, boardY := x+p.Offset.X, y+p.Offset.Y
+			if b != 0 && (boardX < 0 || boardX >= width || boardY < 0 || boardY >= height) {
+				outside++
+			}
+		}
+	}
+	return outside
+}
 
-	for _, b := range image {
-		io.channels.input <- b
+// checkFit returns an error if an image would be cut off or padded to fit the board without Crop being set.
+// A bigger board is fine for an image placed at an offset, as long as none of its alive cells are left out.
+func checkFit(p Params, image [][]byte, width, height int) error {
+	if outside := cellsOutside(p, image, width, height); outside > 0 {
+		return fmt.Errorf("%d alive cells are outside the %dx%d board, give a bigger board or a different offset, or crop it", outside, width, height)
+	}
+	imageWidth := 0
+	if len(image) > 0 {
+		imageWidth = len(image[0])
 	}
+	if p.Offset == (util.Cell{}) && (imageWidth != width || len(image) != height) {
+		return fmt.Errorf("the board is %dx%d but the file is %dx%d, give an offset to place it on the board, or crop it", width, height, imageWidth, len(image))
+	}
+	return nil
+}
 
-	fmt.Println("File", filename, "input done!")
+// boardSize works out the size of the board for an image.
+// Sizes given in the params are used, otherwise the board fits the image at the offset.
+func boardSize(p Params, image [][]byte) (width, height int) {
+	width, height = p.ImageWidth, p.ImageHeight
+	if width == 0 {
+		if len(image) > 0 {
+			width = len(image[0])
+		}
+		width += p.Offset.X
+	}
+	if height == 0 {
+		height = len(image) + p.Offset.Y
+	}
+	return
+}
+
+// BoardSize returns the width and height of the board a game will use.
+// Sizes missing from the params are taken from the input file.
+func BoardSize(p Params) (width, height int, err error) {
+	if p.ImageWidth != 0 && p.ImageHeight != 0 {
+		return p.ImageWidth, p.ImageHeight, nil
+	}
+	if p.InputFile == "" {
+		return 0, 0, errors.New("the board size must be given when there is no input file")
+	}
+	image, _, err := readInputFile(p.InputFile, p.Threshold)
+	if err != nil {
+		return 0, 0, err
+	}
+	width, height = boardSize(p, image)
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("the board would be %dx%d, give the board size for empty patterns", width, height)
+	}
+	return width, height, nil
 }
 
 // startIo should be the entrypoint of the io goroutine.
@@ -138,9 +311,9 @@ func startIo(p Params, c ioChannels) {
 		case command := <-io.channels.command:
 			switch command {
 			case ioInput:
-				io.readPgmImage()
+				io.readImage()
 			case ioOutput:
-				io.writePgmImage(true)
+				io.writeImage()
 			case ioCheckIdle:
 				io.channels.idle <- true
 			}
@@ -0,0 +1,35 @@
+package gol
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestCheckFit checks an input file is only cut off or padded to fit the board when that was asked for
+func TestCheckFit(t *testing.T) {
+	glider := worldFromRows(".O.", "..O", "OOO")
+	edge := worldFromRows("O..", "...", "...")
+	tests := []struct {
+		name          string
+		image         [][]byte
+		width, height int
+		offset        util.Cell
+		ok            bool
+	}{
+		{"same size", glider, 3, 3, util.Cell{}, true},
+		{"bigger board", glider, 10, 10, util.Cell{}, false},
+		{"smaller board", glider, 2, 3, util.Cell{}, false},
+		{"dead cells cut off", edge, 1, 1, util.Cell{}, false},
+		{"bigger board at an offset", glider, 10, 10, util.Cell{X: 2, Y: 3}, true},
+		{"offset past the edge", glider, 10, 10, util.Cell{X: 8, Y: 0}, false},
+		{"negative offset", glider, 10, 10, util.Cell{X: -1, Y: 0}, false},
+		{"dead cells past the edge at an offset", edge, 5, 5, util.Cell{X: 4, Y: 4}, true},
+	}
+	for _, test := range tests {
+		err := checkFit(Params{Offset: test.offset}, test.image, test.width, test.height)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: expected ok to be %v, got %v", test.name, test.ok, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gol
+
+import (
+	"net/rpc"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// Watch a game on the server until it stops or 'q' is pressed
+// The server sends the same reports as it does to the controller running the game,
+// but keypresses other than 'q' are ignored as observers can't change the game
+func observeGame(p Params, c controllerChannels, server *rpc.Client, controller *Controller) {
+	response := new(stubs.ObserveResponse)
+	err := server.Call(stubs.ServerObserve, stubs.ObserveRequest{
+		ObserverAddress: p.OurIP + ":" + p.Port,
+		GameID:          p.GameID,
+		Height:          p.ImageHeight,
+		Width:           p.ImageWidth,
+		VisualUpdates:   p.VisualUpdates,
+		FrameRate:       p.FrameRate,
+	}, response)
+	if err != nil {
+		println("Connection error:", err.Error())
+		return
+	}
+	if !response.Success {
+		println("Server error:", response.Message)
+		return
+	}
+	println("Watching game", response.GameID, "from turn", response.Turn)
+	controller.start(response.Turn, response.Height, response.Width, response.Rule, response.Topology)
+
+	for {
+		select {
+		case key := <-c.keypresses:
+			if isCountKey(key) {
+				continue
+			}
+			if key != 'q' {
+				println("Observers can't control the game")
+				continue
+			}
+			println("Detaching from game", response.GameID)
+			detachResponse := new(stubs.ServerResponse)
+			err = server.Call(stubs.ServerDetach, stubs.DetachRequest{GameID: response.GameID, ObserverID: response.ObserverID}, detachResponse)
+			if err != nil {
+				println("Error detaching from the server:", err.Error())
+			} else if !detachResponse.Success {
+				println("Server error:", detachResponse.Message)
+			}
+			return
+		case <-controller.timeoutTimer.C:
+			if !serverAlive(server) {
+				return
+			}
+			controller.timeoutTimer.Reset(5 * time.Second)
+		case <-controller.stopChan:
+			println("Received stop signal")
+			return
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package gol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// This file contains readers and writers for the pattern formats shared by the Life community
+// See https://conwaylife.com/wiki/File_formats for descriptions of each format
+
+// FileFormat is a format the board can be read from or saved in
+type FileFormat int
+
+const (
+	// PGM is a greyscale image where any non-zero pixel is alive
+	// Any PNM image (P1, P2, P4 or P5) can be read as either PGM or PBM
+	PGM FileFormat = iota
+	// RLE is the run length encoded format used by most pattern libraries
+	RLE
+	// Life106 lists the coordinates of every alive cell
+	Life106
+	// Plaintext draws the pattern with '.' for dead cells and 'O' for alive ones (.cells files)
+	Plaintext
+	// PBM is a bitmap where set (black) pixels are alive
+	PBM
+	// PNG is a colour image where cells are coloured by their age, it can only be written
+	PNG
+)
+
+// ParseFileFormat converts a format name or file extension (e.g. "rle") into a FileFormat
+func ParseFileFormat(s string) (FileFormat, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), ".") {
+	case "pgm", "pnm", "":
+		return PGM, nil
+	case "pbm":
+		return PBM, nil
+	case "png":
+		return PNG, nil
+	case "rle":
+		return RLE, nil
+	case "life106", "life", "lif", "1.06":
+		return Life106, nil
+	case "plaintext", "cells", "txt":
+		return Plaintext, nil
+	}
+	return PGM, errors.New("unknown file format " + s)
+}
+
+// String returns the name of the format
+func (f FileFormat) String() string {
+	switch f {
+	case PGM:
+		return "PGM"
+	case RLE:
+		return "RLE"
+	case Life106:
+		return "Life 1.06"
+	case Plaintext:
+		return "Plaintext"
+	case PBM:
+		return "PBM"
+	case PNG:
+		return "PNG"
+	default:
+		return "Incorrect Format"
+	}
+}
+
+// Extension returns the file extension used for the format
+func (f FileFormat) Extension() string {
+	switch f {
+	case RLE:
+		return ".rle"
+	case Life106:
+		return ".lif"
+	case Plaintext:
+		return ".cells"
+	case PBM:
+		return ".pbm"
+	case PNG:
+		return ".png"
+	default:
+		return ".pgm"
+	}
+}
+
+// pattern is a set of alive cells read from a pattern file
+// The cells are moved so the top left of the pattern is (0, 0)
+// rule is nil unless the file gives one
+type pattern struct {
+	width, height int
+	cells         []util.Cell
+	rule          *stubs.Rule
+}
+
+// Work out the format of a file from its extension, or from its contents if the extension isn't known
+func detectFileFormat(filename string, data []byte) FileFormat {
+	if format, err := ParseFileFormat(filepath.Ext(filename)); err == nil && filepath.Ext(filename) != "" {
+		return format
+	}
+
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(string(data), "\x89PNG"):
+		return PNG
+	case strings.HasPrefix(text, "P1"), strings.HasPrefix(text, "P4"):
+		return PBM
+	case strings.HasPrefix(text, "P2"), strings.HasPrefix(text, "P5"):
+		return PGM
+	case strings.HasPrefix(text, "#Life 1.06"):
+		return Life106
+	case strings.HasPrefix(text, "!"):
+		return Plaintext
+	}
+	// RLE files have a header line starting with "x ="
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.Replace(line, " ", "", -1), "x=") {
+			return RLE
+		}
+		break
+	}
+	return Plaintext
+}
+
+// Read a pattern in any of the pattern formats
+func readPattern(format FileFormat, r io.Reader) (*pattern, error) {
+	switch format {
+	case RLE:
+		return readRLE(r)
+	case Life106:
+		return readLife106(r)
+	case Plaintext:
+		return readPlaintext(r)
+	}
+	return nil, errors.New(format.String() + " is not a pattern format")
+}
+
+// Read a pattern in RLE format
+// The rule is taken from the header line if it has one
+func readRLE(r io.Reader) (*pattern, error) {
+	p := new(pattern)
+	scanner := bufio.NewScanner(r)
+	headerRead := false
+	row, col, count := 0, 0, 0
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !headerRead {
+			err := p.readRLEHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			headerRead = true
+			continue
+		}
+
+		for _, c := range line {
+			switch {
+			case c >= '0' && c <= '9':
+				count = count*10 + int(c-'0')
+			case c == ' ' || c == '\t':
+			case c == '!':
+				return p, nil
+			default:
+				run := count
+				if run == 0 {
+					run = 1
+				}
+				count = 0
+				switch c {
+				case '$':
+					row += run
+					col = 0
+				case 'b', '.':
+					col += run
+				default:
+					// Every other letter is an alive state
+					for i := 0; i < run; i++ {
+						p.cells = append(p.cells, util.Cell{X: col + i, Y: row})
+					}
+					col += run
+				}
+				if col > p.width {
+					p.width = col
+				}
+				if row+1 > p.height && col > 0 {
+					p.height = row + 1
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !headerRead {
+		return nil, errors.New("RLE file has no header")
+	}
+	return p, nil
+}
+
+// Read the "x = 3, y = 3, rule = B3/S23" header line of an RLE file
+func (p *pattern) readRLEHeader(line string) error {
+	for i, field := range strings.Split(line, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 && i > 0 {
+			// The grid after a rule (e.g. "B3/S23:T10,10") can contain commas
+			continue
+		} else if len(parts) != 2 {
+			return errors.New("invalid RLE header " + line)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		var err error
+		switch key {
+		case "x":
+			p.width, err = strconv.Atoi(value)
+		case "y":
+			p.height, err = strconv.Atoi(value)
+		case "rule":
+			// Anything after a colon describes the grid, which we set ourselves
+			var rule stubs.Rule
+			rule, err = stubs.ParseRule(strings.SplitN(value, ":", 2)[0])
+			p.rule = &rule
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read a pattern in Life 1.06 format
+func readLife106(r io.Reader) (*pattern, error) {
+	p := new(pattern)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.New("invalid Life 1.06 line " + line)
+		}
+		x, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		p.cells = append(p.cells, util.Cell{X: x, Y: y})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Coordinates can be negative, so move the pattern to start at (0, 0)
+	if len(p.cells) == 0 {
+		return p, nil
+	}
+	minX, minY, maxX, maxY := p.cells[0].X, p.cells[0].Y, p.cells[0].X, p.cells[0].Y
+	for _, cell := range p.cells {
+		if cell.X < minX {
+			minX = cell.X
+		} else if cell.X > maxX {
+			maxX = cell.X
+		}
+		if cell.Y < minY {
+			minY = cell.Y
+		} else if cell.Y > maxY {
+			maxY = cell.Y
+		}
+	}
+	for i := range p.cells {
+		p.cells[i].X -= minX
+		p.cells[i].Y -= minY
+	}
+	p.width, p.height = maxX-minX+1, maxY-minY+1
+	return p, nil
+}
+
+// Read a pattern in plaintext (.cells) format
+func readPlaintext(r io.Reader) (*pattern, error) {
+	p := new(pattern)
+	scanner := bufio.NewScanner(r)
+	row := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		for col, c := range line {
+			switch c {
+			case '.':
+			case 'O', 'o', '*':
+				p.cells = append(p.cells, util.Cell{X: col, Y: row})
+			default:
+				return nil, fmt.Errorf("invalid character %q in plaintext pattern", c)
+			}
+		}
+		if len(line) > p.width {
+			p.width = len(line)
+		}
+		row++
+		p.height = row
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Write a board in one of the pattern formats
+func writePattern(format FileFormat, w io.Writer, name string, world [][]byte, rule stubs.Rule) error {
+	buffer := bufio.NewWriter(w)
+	switch format {
+	case RLE:
+		writeRLE(buffer, name, world, rule)
+	case Life106:
+		writeLife106(buffer, world)
+	case Plaintext:
+		writePlaintext(buffer, name, world)
+	default:
+		return errors.New(format.String() + " is not a pattern format")
+	}
+	return buffer.Flush()
+}
+
+// Write a board in RLE format, including the rule in the header
+func writeRLE(w *bufio.Writer, name string, world [][]byte, rule stubs.Rule) {
+	height, width := len(world), 0
+	if height > 0 {
+		width = len(world[0])
+	}
+	fmt.Fprintf(w, "#N %s\n", name)
+	fmt.Fprintf(w, "x = %d, y = %d, rule = %s\n", width, height, rule)
+
+	// Lines of an RLE file shouldn't be longer than 70 characters
+	line := ""
+	add := func(run int, tag byte) {
+		item := string(tag)
+		if run > 1 {
+			item = strconv.Itoa(run) + item
+		}
+		if len(line)+len(item) > 70 {
+			fmt.Fprintln(w, line)
+			line = ""
+		}
+		line += item
+	}
+
+	// Each $ moves down a row from the last row written, which starts on the first row
+	lastRow := 0
+	for y, row := range world {
+		// Trailing dead cells on a row are left out, as are empty rows
+		end := len(row)
+		for end > 0 && row[end-1] == 0 {
+			end--
+		}
+		if end == 0 {
+			continue
+		}
+		if y > lastRow {
+			add(y-lastRow, '$')
+		}
+		lastRow = y
+
+		for x := 0; x < end; {
+			alive := row[x] != 0
+			run := 0
+			for x < end && (row[x] != 0) == alive {
+				run++
+				x++
+			}
+			if alive {
+				add(run, 'o')
+			} else {
+				add(run, 'b')
+			}
+		}
+	}
+	line += "!"
+	fmt.Fprintln(w, line)
+}
+
+// Write a board in Life 1.06 format
+func writeLife106(w *bufio.Writer, world [][]byte) {
+	fmt.Fprintln(w, "#Life 1.06")
+	for y, row := range world {
+		for x, cell := range row {
+			if cell != 0 {
+				fmt.Fprintf(w, "%d %d\n", x, y)
+			}
+		}
+	}
+}
+
+// Write a board in plaintext (.cells) format
+func writePlaintext(w *bufio.Writer, name string, world [][]byte) {
+	fmt.Fprintf(w, "!Name: %s\n", name)
+	for _, row := range world {
+		for _, cell := range row {
+			if cell != 0 {
+				w.WriteByte('O')
+			} else {
+				w.WriteByte('.')
+			}
+		}
+		w.WriteByte('\n')
+	}
+}
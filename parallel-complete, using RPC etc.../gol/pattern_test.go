@@ -0,0 +1,171 @@
+package gol
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Make a world from rows of '.' and 'O'
+func worldFromRows(rows ...string) [][]byte {
+	world := make([][]byte, len(rows))
+	for y, row := range rows {
+		world[y] = make([]byte, len(row))
+		for x, c := range row {
+			if c == 'O' {
+				world[y][x] = 255
+			}
+		}
+	}
+	return world
+}
+
+// Get the alive cells of a world, moved so the first alive row and column are at 0 if trim is set
+func worldCells(world [][]byte, trim bool) []util.Cell {
+	var cells []util.Cell
+	minX, minY := 0, 0
+	if trim {
+		minX, minY = -1, -1
+		for y, row := range world {
+			for x, cell := range row {
+				if cell == 0 {
+					continue
+				}
+				if minY < 0 {
+					minY = y
+				}
+				if minX < 0 || x < minX {
+					minX = x
+				}
+			}
+		}
+	}
+	for y, row := range world {
+		for x, cell := range row {
+			if cell != 0 {
+				cells = append(cells, util.Cell{X: x - minX, Y: y - minY})
+			}
+		}
+	}
+	return cells
+}
+
+func equalCells(a, b []util.Cell) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[util.Cell]bool)
+	for _, cell := range a {
+		seen[cell] = true
+	}
+	for _, cell := range b {
+		if !seen[cell] {
+			return false
+		}
+	}
+	return true
+}
+
+var patternTests = []struct {
+	name  string
+	world [][]byte
+}{
+	{"glider", worldFromRows(".O.", "..O", "OOO")},
+	{"leading empty rows", worldFromRows("......", "......", "......", "..O...", ".OO...", "......")},
+	{"trailing empty rows", worldFromRows("O.O...", "......", ".O....", "......", "......")},
+	{"empty rows in between", worldFromRows("O.....", "......", "......", ".....O")},
+	{"last row", worldFromRows("....", "....", "....", "...O")},
+	{"empty", worldFromRows("....", "....")},
+	{"long row", worldFromRows(strings.Repeat("O.OO..", 30), strings.Repeat(".", 180), strings.Repeat("OOO.", 45))},
+}
+
+// TestPatternRoundTrip writes boards in each pattern format and checks they are read back the same
+// Life 1.06 only stores the alive cells, so its patterns start at the first alive row and column
+func TestPatternRoundTrip(t *testing.T) {
+	rule, err := stubs.ParseRule("B36/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []FileFormat{RLE, Life106, Plaintext} {
+		for _, test := range patternTests {
+			t.Run(format.String()+"/"+test.name, func(t *testing.T) {
+				var file bytes.Buffer
+				err := writePattern(format, &file, test.name, test.world, rule)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if detected := detectFileFormat("pattern", file.Bytes()); detected != format {
+					t.Errorf("Expected the written file to be detected as %v, got %v", format, detected)
+				}
+				p, err := readPattern(format, bytes.NewReader(file.Bytes()))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expected := worldCells(test.world, format == Life106)
+				if !equalCells(p.cells, expected) {
+					t.Errorf("Expected cells %v, got %v\n%s", expected, p.cells, file.String())
+				}
+				if format != Life106 && (p.width != len(test.world[0]) || p.height != len(test.world)) {
+					t.Errorf("Expected a %dx%d pattern, got %dx%d\n%s", len(test.world[0]), len(test.world), p.width, p.height, file.String())
+				}
+				if format == RLE && (p.rule == nil || *p.rule != rule) {
+					t.Errorf("Expected rule %v, got %v", rule, p.rule)
+				}
+			})
+		}
+	}
+}
+
+// TestWriteRLE checks the runs written for rows and the gaps between them
+func TestWriteRLE(t *testing.T) {
+	tests := []struct {
+		world    [][]byte
+		expected string
+	}{
+		{worldFromRows(".O.", "..O", "OOO"), "bo$2bo$3o!"},
+		{worldFromRows("...", "...", "...", ".OO"), "3$b2o!"},
+		{worldFromRows("O..", "...", "...", "..O", "..."), "o3$2bo!"},
+		{worldFromRows("...", "..."), "!"},
+	}
+	for _, test := range tests {
+		var file bytes.Buffer
+		err := writePattern(RLE, &file, "test", test.world, stubs.Rule{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+		if got := lines[len(lines)-1]; got != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, got)
+		}
+	}
+}
+
+// TestRLERule checks a rule in the header is kept even if nothing is born or survives, and is nil without one
+func TestRLERule(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"x = 3, y = 1, rule = B36/S23\n3o!\n", "B36/S23"},
+		{"x = 3, y = 1, rule = B/S\n3o!\n", "B/S"},
+		{"x = 3, y = 1, rule = 23/3:T3,1\n3o!\n", "B3/S23"},
+		{"x = 3, y = 1\n3o!\n", ""},
+	}
+	for _, test := range tests {
+		p, err := readPattern(RLE, strings.NewReader(test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.expected == "" {
+			if p.rule != nil {
+				t.Errorf("Expected no rule from %q, got %v", test.file, *p.rule)
+			}
+		} else if p.rule == nil || p.rule.String() != test.expected {
+			t.Errorf("Expected rule %s from %q, got %v", test.expected, test.file, p.rule)
+		}
+	}
+}
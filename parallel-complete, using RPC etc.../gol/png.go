@@ -0,0 +1,109 @@
+package gol
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// This file writes boards as PNG images, with each cell coloured by how long it has been alive
+// Newborn cells are white, turning yellow, red and purple as they age and blue once they are stable
+// Cells which died recently can be left as a fading trail, so moving patterns show where they have been
+
+// Colours alive cells pass through as they age, spaced evenly by the log of their age
+var ageColours = []color.RGBA{
+	{255, 255, 255, 255}, // 1 turn
+	{255, 240, 120, 255}, // 2 turns
+	{255, 170, 40, 255},  // 4 turns
+	{240, 90, 40, 255},   // 8 turns
+	{210, 40, 60, 255},   // 16 turns
+	{160, 40, 160, 255},  // 32 turns
+	{90, 60, 210, 255},   // 64 turns
+	{40, 90, 230, 255},   // 128 turns or more
+}
+
+// Colour of a cell which has just died, trails fade from this to black
+var trailColour = color.RGBA{40, 130, 140, 255}
+
+// Number of colours in the palette for alive cells and for trails
+const (
+	ageLevels   = 128
+	trailLevels = 64
+)
+
+// The palette is black, then the alive colours, then the trail colours
+var agePalette = makeAgePalette()
+
+func makeAgePalette() color.Palette {
+	palette := color.Palette{color.RGBA{0, 0, 0, 255}}
+	for level := 0; level < ageLevels; level++ {
+		// Find the two colours this level is between
+		position := float64(level) / (ageLevels - 1) * float64(len(ageColours)-1)
+		i := int(position)
+		if i >= len(ageColours)-1 {
+			palette = append(palette, ageColours[len(ageColours)-1])
+			continue
+		}
+		palette = append(palette, mixColours(ageColours[i], ageColours[i+1], position-float64(i)))
+	}
+	for level := 0; level < trailLevels; level++ {
+		palette = append(palette, mixColours(color.RGBA{0, 0, 0, 255}, trailColour, float64(level+1)/trailLevels))
+	}
+	return palette
+}
+
+// Mix two colours, with fraction of the way from a to b
+func mixColours(a, b color.RGBA, fraction float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*fraction + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// Work out the palette index for a cell
+// Alive cells without an age are drawn as newborn, and dead cells are only drawn for trail turns after they die
+func ageIndex(alive bool, age int16, trail int) uint8 {
+	if alive {
+		if age <= 1 {
+			return 1
+		}
+		maxAge := math.Exp2(float64(len(ageColours) - 1))
+		level := int(math.Log2(float64(age)) / math.Log2(maxAge) * (ageLevels - 1))
+		if level > ageLevels-1 {
+			level = ageLevels - 1
+		}
+		return uint8(1 + level)
+	}
+
+	dead := -int(age)
+	if age >= 0 || dead > trail {
+		return 0
+	}
+	// The trail is brightest the turn after the cell dies
+	// Rounding up keeps the level between 0 and trailLevels-1 however long the trail is
+	level := ((trail-dead+1)*trailLevels - 1) / trail
+	return uint8(1 + ageLevels + level)
+}
+
+// writePngImage writes a board to a png file, colouring cells by their age.
+// ages can be nil, in which case alive cells are white and there are no trails.
+func writePngImage(w io.Writer, world [][]byte, ages [][]int16, trail int) error {
+	width := 0
+	if len(world) > 0 {
+		width = len(world[0])
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, width, len(world)), agePalette)
+	for y, row := range world {
+		for x, cell := range row {
+			var age int16
+			if y < len(ages) && x < len(ages[y]) {
+				age = ages[y][x]
+			}
+			img.Pix[img.PixOffset(x, y)] = ageIndex(cell != 0, age, trail)
+		}
+	}
+	return png.Encode(w, img)
+}
@@ -0,0 +1,111 @@
+package gol
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestAgeIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		alive    bool
+		age      int16
+		trail    int
+		expected uint8
+	}{
+		{"alive without an age", true, 0, 8, 1},
+		{"newborn", true, 1, 8, 1},
+		{"2 turns", true, 2, 8, 19},
+		{"64 turns", true, 64, 8, 109},
+		{"128 turns", true, 128, 8, ageLevels},
+		{"oldest", true, math.MaxInt16, 8, ageLevels},
+		{"never alive", false, 0, 8, 0},
+		{"just died", false, -1, 8, ageLevels + trailLevels},
+		{"end of the trail", false, -8, 8, ageLevels + 8},
+		{"after the trail", false, -9, 8, 0},
+		{"no trail", false, -1, 0, 0},
+		{"trail of 1", false, -1, 1, ageLevels + trailLevels},
+		{"end of a long trail", false, -200, 200, ageLevels + 1},
+		{"longest trail", false, math.MinInt16, -math.MinInt16, ageLevels + 1},
+	}
+	for _, test := range tests {
+		if index := ageIndex(test.alive, test.age, test.trail); index != test.expected {
+			t.Errorf("%s: expected %d, got %d", test.name, test.expected, index)
+		}
+	}
+}
+
+// TestAgeIndexRanges checks alive cells always get alive colours which never get younger,
+// and dead cells get trail colours which fade every turn, for any length of trail
+func TestAgeIndexRanges(t *testing.T) {
+	previous := uint8(0)
+	for age := 1; age <= math.MaxInt16; age++ {
+		index := ageIndex(true, int16(age), 0)
+		if index < 1 || index > ageLevels || index < previous {
+			t.Fatalf("Age %d has colour %d after %d", age, index, previous)
+		}
+		previous = index
+	}
+
+	for trail := 1; trail <= 300; trail++ {
+		previous := uint8(math.MaxUint8)
+		for dead := 1; dead <= trail; dead++ {
+			index := ageIndex(false, int16(-dead), trail)
+			if index <= ageLevels || index > ageLevels+trailLevels || index > previous {
+				t.Fatalf("Trail of %d: dead for %d turns has colour %d after %d", trail, dead, index, previous)
+			}
+			previous = index
+		}
+		if ageIndex(false, int16(-trail-1), trail) != 0 {
+			t.Fatalf("Trail of %d: expected cells dead for longer to be black", trail)
+		}
+	}
+}
+
+func TestWritePngImage(t *testing.T) {
+	world := [][]byte{
+		{255, 0, 0},
+		{0, 255, 0},
+	}
+	ages := [][]int16{
+		{1, -1, -3},
+		{0, 200, 0},
+	}
+	tests := []struct {
+		name     string
+		ages     [][]int16
+		trail    int
+		expected [][]uint8
+	}{
+		{"ages", ages, 2, [][]uint8{{1, ageLevels + trailLevels, 0}, {0, ageLevels, 0}}},
+		{"without ages", nil, 2, [][]uint8{{1, 0, 0}, {0, 1, 0}}},
+	}
+	for _, test := range tests {
+		var file bytes.Buffer
+		err := writePngImage(&file, world, test.ages, test.trail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := png.Decode(&file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, ok := decoded.(*image.Paletted)
+		if !ok {
+			t.Fatalf("%s: expected a paletted image, got %T", test.name, decoded)
+		}
+		if img.Bounds() != image.Rect(0, 0, 3, 2) {
+			t.Fatalf("%s: expected a 3x2 image, got %v", test.name, img.Bounds())
+		}
+		for y, row := range test.expected {
+			for x, expected := range row {
+				if index := img.ColorIndexAt(x, y); index != expected {
+					t.Errorf("%s: expected colour %d at (%d, %d), got %d", test.name, expected, x, y, index)
+				}
+			}
+		}
+	}
+}
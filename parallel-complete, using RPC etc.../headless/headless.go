@@ -0,0 +1,173 @@
+package headless
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// This package runs the Game of Life without SDL, writing every event as a line of JSON
+// Every line has a "type" (the name of the event) and "completedTurns", the rest depends on the type:
+//	GameStarted         width, height
+//	AliveCellsCount     cellsCount
+//	ActiveTilesCount    activeTiles, totalTiles
+//	ImageOutputComplete filename
+//	IOError             filename, error
+//	StateChange         newState ("Paused", "Executing" or "Quitting")
+//	CellFlipped         cell {x, y}
+//	TurnComplete        skippedTurns
+//	FinalTurnComplete   aliveCount, alive [{x, y}, ...]
+// Any other event has its String() as "message"
+// New fields may be added, but existing ones won't be renamed or removed
+
+// cell is a cell in JSON
+type cell struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Records for each type of event
+type gameStarted struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type aliveCellsCount struct {
+	CellsCount int `json:"cellsCount"`
+}
+
+type activeTilesCount struct {
+	ActiveTiles int `json:"activeTiles"`
+	TotalTiles  int `json:"totalTiles"`
+}
+
+type imageOutputComplete struct {
+	Filename string `json:"filename"`
+}
+
+type ioError struct {
+	Filename string `json:"filename"`
+	Error    string `json:"error"`
+}
+
+type stateChange struct {
+	NewState string `json:"newState"`
+}
+
+type cellFlipped struct {
+	Cell cell `json:"cell"`
+}
+
+type turnComplete struct {
+	SkippedTurns int `json:"skippedTurns"`
+}
+
+type finalTurnComplete struct {
+	AliveCount int    `json:"aliveCount"`
+	Alive      []cell `json:"alive"`
+}
+
+type otherEvent struct {
+	Message string `json:"message"`
+}
+
+// record is the line written for an event
+// The fields of the event's own record are flattened into it
+type record struct {
+	Type           string
+	CompletedTurns int
+	Fields         interface{}
+}
+
+// MarshalJSON writes the type and turn, followed by the event's own fields
+func (r record) MarshalJSON() ([]byte, error) {
+	header, err := json.Marshal(struct {
+		Type           string `json:"type"`
+		CompletedTurns int    `json:"completedTurns"`
+	}{r.Type, r.CompletedTurns})
+	if err != nil || r.Fields == nil {
+		return header, err
+	}
+
+	fields, err := json.Marshal(r.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) <= 2 {
+		// The event has no fields of its own
+		return header, nil
+	}
+	// Join the two objects, replacing the closing brace of the header with a comma
+	joined := append(header[:len(header)-1], ',')
+	return append(joined, fields[1:]...), nil
+}
+
+// Encode converts an event into its JSON line, without the newline
+func Encode(event gol.Event) ([]byte, error) {
+	r := record{
+		Type:           reflect.TypeOf(event).Name(),
+		CompletedTurns: event.GetCompletedTurns(),
+	}
+
+	switch e := event.(type) {
+	case gol.GameStarted:
+		r.Fields = gameStarted{Width: e.Width, Height: e.Height}
+	case gol.AliveCellsCount:
+		r.Fields = aliveCellsCount{CellsCount: e.CellsCount}
+	case gol.ActiveTilesCount:
+		r.Fields = activeTilesCount{ActiveTiles: e.ActiveTiles, TotalTiles: e.TotalTiles}
+	case gol.ImageOutputComplete:
+		r.Fields = imageOutputComplete{Filename: e.Filename}
+	case gol.IOError:
+		r.Fields = ioError{Filename: e.Filename, Error: e.Err.Error()}
+	case gol.StateChange:
+		r.Fields = stateChange{NewState: e.NewState.String()}
+	case gol.CellFlipped:
+		r.Fields = cellFlipped{Cell: cell{X: e.Cell.X, Y: e.Cell.Y}}
+	case gol.TurnComplete:
+		r.Fields = turnComplete{SkippedTurns: e.SkippedTurns}
+	case gol.FinalTurnComplete:
+		r.Fields = finalTurnComplete{AliveCount: len(e.Alive), Alive: cells(e.Alive)}
+	default:
+		r.Fields = otherEvent{Message: event.String()}
+	}
+	return json.Marshal(r)
+}
+
+// Convert alive cells to JSON cells, so an empty board is [] rather than null
+func cells(alive []util.Cell) []cell {
+	converted := make([]cell, len(alive))
+	for i, c := range alive {
+		converted[i] = cell{X: c.X, Y: c.Y}
+	}
+	return converted
+}
+
+// Run writes every event to out as a line of JSON until the events channel is closed
+// Events are still drained if out fails, so the game is never left waiting
+// It returns the error from writing to out, or the error of the first IOError event so callers can tell the run failed
+func Run(events <-chan gol.Event, out io.Writer) error {
+	var writeErr, runErr error
+	for event := range events {
+		if e, ok := event.(gol.IOError); ok && runErr == nil {
+			runErr = e.Err
+		}
+		if writeErr != nil {
+			continue
+		}
+
+		line, err := Encode(event)
+		if err == nil {
+			_, err = out.Write(append(line, '\n'))
+		}
+		writeErr = err
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+	return runErr
+}
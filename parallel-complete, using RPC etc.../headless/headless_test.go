@@ -0,0 +1,84 @@
+package headless
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// message is an event the headless package doesn't know about
+type message struct{}
+
+func (message) String() string         { return "Hello" }
+func (message) GetCompletedTurns() int { return 3 }
+
+// TestEncode checks the line written for every type of event matches the schema documented in headless.go
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		event    gol.Event
+		expected string
+	}{
+		{gol.GameStarted{CompletedTurns: 30, Width: 64, Height: 16},
+			`{"type":"GameStarted","completedTurns":30,"width":64,"height":16}`},
+		{gol.AliveCellsCount{CompletedTurns: 2, CellsCount: 5},
+			`{"type":"AliveCellsCount","completedTurns":2,"cellsCount":5}`},
+		{gol.ActiveTilesCount{CompletedTurns: 2, ActiveTiles: 3, TotalTiles: 16},
+			`{"type":"ActiveTilesCount","completedTurns":2,"activeTiles":3,"totalTiles":16}`},
+		{gol.ImageOutputComplete{CompletedTurns: 10, Filename: "out/16x16x10.pgm"},
+			`{"type":"ImageOutputComplete","completedTurns":10,"filename":"out/16x16x10.pgm"}`},
+		{gol.IOError{CompletedTurns: 0, Filename: "glider.rle", Err: errors.New("no such file")},
+			`{"type":"IOError","completedTurns":0,"filename":"glider.rle","error":"no such file"}`},
+		{gol.StateChange{CompletedTurns: 4, NewState: stubs.Paused},
+			`{"type":"StateChange","completedTurns":4,"newState":"Paused"}`},
+		{gol.StateChange{CompletedTurns: 4, NewState: stubs.Executing},
+			`{"type":"StateChange","completedTurns":4,"newState":"Executing"}`},
+		{gol.StateChange{CompletedTurns: 4, NewState: stubs.Quitting},
+			`{"type":"StateChange","completedTurns":4,"newState":"Quitting"}`},
+		{gol.CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: 7, Y: 9}},
+			`{"type":"CellFlipped","completedTurns":1,"cell":{"x":7,"y":9}}`},
+		{gol.TurnComplete{CompletedTurns: 8, SkippedTurns: 2},
+			`{"type":"TurnComplete","completedTurns":8,"skippedTurns":2}`},
+		{gol.FinalTurnComplete{CompletedTurns: 10, Alive: []util.Cell{{X: 1, Y: 2}, {X: 3, Y: 4}}},
+			`{"type":"FinalTurnComplete","completedTurns":10,"aliveCount":2,"alive":[{"x":1,"y":2},{"x":3,"y":4}]}`},
+		// An empty board is an empty list rather than null
+		{gol.FinalTurnComplete{CompletedTurns: 10},
+			`{"type":"FinalTurnComplete","completedTurns":10,"aliveCount":0,"alive":[]}`},
+		{message{},
+			`{"type":"message","completedTurns":3,"message":"Hello"}`},
+	}
+	for _, test := range tests {
+		line, err := Encode(test.event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, line)
+		}
+	}
+}
+
+// TestRun checks every event is written on its own line, and the first IOError is returned once the events end
+func TestRun(t *testing.T) {
+	first := errors.New("first")
+	events := make(chan gol.Event, 4)
+	events <- gol.TurnComplete{CompletedTurns: 1}
+	events <- gol.IOError{CompletedTurns: 1, Filename: "a", Err: first}
+	events <- gol.IOError{CompletedTurns: 1, Filename: "b", Err: errors.New("second")}
+	events <- gol.FinalTurnComplete{CompletedTurns: 1}
+	close(events)
+
+	var out bytes.Buffer
+	err := Run(events, &out)
+	if err != first {
+		t.Errorf("Expected the first IOError to be returned, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Errorf("Expected 4 lines, got %d:\n%s", len(lines), out.String())
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/headless"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// headlessLine is the schema of a line written in headless mode, as documented in the headless package
+type headlessLine struct {
+	Type           string `json:"type"`
+	CompletedTurns int    `json:"completedTurns"`
+
+	CellsCount  int    `json:"cellsCount"`
+	ActiveTiles int    `json:"activeTiles"`
+	TotalTiles  int    `json:"totalTiles"`
+	Filename    string `json:"filename"`
+	Error       string `json:"error"`
+	NewState    string `json:"newState"`
+	Cell        struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"cell"`
+	SkippedTurns int `json:"skippedTurns"`
+	AliveCount   int `json:"aliveCount"`
+	Alive        []struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"alive"`
+	Message string `json:"message"`
+}
+
+// headlessFields are the fields each type of line must have, besides type and completedTurns
+var headlessFields = map[string][]string{
+	"AliveCellsCount":     {"cellsCount"},
+	"ActiveTilesCount":    {"activeTiles", "totalTiles"},
+	"ImageOutputComplete": {"filename"},
+	"IOError":             {"filename", "error"},
+	"StateChange":         {"newState"},
+	"CellFlipped":         {"cell"},
+	"TurnComplete":        {"skippedTurns"},
+	"FinalTurnComplete":   {"aliveCount", "alive"},
+}
+
+// TestHeadless runs a 16x16 game for 100 turns in headless mode and checks every line matches the documented schema,
+// and that the board drawn from the lines is the same as the final board
+func TestHeadless(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100, Threads: 4, VisualUpdates: true}
+		events := make(chan gol.Event)
+		go gol.Run(p, events, nil)
+		var out bytes.Buffer
+		err := headless.Run(events, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		board := make([][]bool, p.ImageHeight)
+		for y := range board {
+			board[y] = make([]bool, p.ImageWidth)
+		}
+		seen := make(map[string]bool)
+		var final *headlessLine
+		scanner := bufio.NewScanner(&out)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var fields map[string]json.RawMessage
+			err := json.Unmarshal(scanner.Bytes(), &fields)
+			if err != nil {
+				t.Fatalf("Invalid JSON line %s: %v", scanner.Text(), err)
+			}
+			line := new(headlessLine)
+			err = json.Unmarshal(scanner.Bytes(), line)
+			if err != nil {
+				t.Fatalf("Line %s doesn't match the schema: %v", scanner.Text(), err)
+			}
+
+			required := []string{"type", "completedTurns"}
+			if documented, ok := headlessFields[line.Type]; ok {
+				required = append(required, documented...)
+			} else {
+				required = append(required, "message")
+			}
+			for _, field := range required {
+				if _, ok := fields[field]; !ok {
+					t.Errorf("%s line is missing %q: %s", line.Type, field, scanner.Text())
+				}
+			}
+			seen[line.Type] = true
+
+			switch line.Type {
+			case "CellFlipped":
+				board[line.Cell.Y][line.Cell.X] = !board[line.Cell.Y][line.Cell.X]
+			case "FinalTurnComplete":
+				final = line
+			case "ImageOutputComplete":
+				// The file can be opened from the name given
+				if _, err := os.Stat(line.Filename); err != nil {
+					t.Errorf("Expected %s to have been saved: %v", line.Filename, err)
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, lineType := range []string{"CellFlipped", "TurnComplete", "FinalTurnComplete", "ImageOutputComplete"} {
+			if !seen[lineType] {
+				t.Errorf("Expected a %s line", lineType)
+			}
+		}
+		if final == nil {
+			return
+		}
+		if final.CompletedTurns != p.Turns || final.AliveCount != len(final.Alive) {
+			t.Errorf("Expected the final line to be on turn %d with %d alive cells, got turn %d with %d",
+				p.Turns, len(final.Alive), final.CompletedTurns, final.AliveCount)
+		}
+		alive := make([]util.Cell, len(final.Alive))
+		for i, cell := range final.Alive {
+			alive[i] = util.Cell{X: cell.X, Y: cell.Y}
+		}
+		expected := util.GetAliveCells(board)
+		if !assertEqualBoard(t, alive, expected, p) {
+			return
+		}
+		assertEqualBoard(t, alive, readAliveCells("check/images/16x16x100.pgm", 16, 16), p)
+	}
+}
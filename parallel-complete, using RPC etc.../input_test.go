@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// glider is a glider heading down and right, which moves one cell diagonally every 4 turns
+var glider = []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+
+// TestPatternInput starts games from pattern files with both worker engines,
+// including boards whose size isn't a multiple of 8 so the bit arrays sent to the workers end part way through a byte.
+func TestPatternInput(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		dir, err := ioutil.TempDir("", "gol-patterns")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		files := map[string]string{
+			"glider.rle":   "#N Glider\nx = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n",
+			"glider.cells": "!Name: Glider\n.O.\n..O\nOOO\n",
+			"empty.lif":    "#Life 1.06\n",
+		}
+		for name, contents := range files {
+			err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		moved := make([]util.Cell, len(glider))
+		for i, cell := range glider {
+			moved[i] = util.Cell{X: cell.X + 11, Y: cell.Y + 11}
+		}
+		tests := []struct {
+			file          string
+			width, height int
+			offset        util.Cell
+			turns         int
+			expected      []util.Cell
+		}{
+			// The board takes the size of the pattern, where every cell neighbours every other cell so the glider dies
+			{"glider.rle", 0, 0, util.Cell{}, 0, glider},
+			{"glider.rle", 0, 0, util.Cell{}, 1, nil},
+			{"glider.cells", 0, 0, util.Cell{}, 0, glider},
+			{"glider.cells", 0, 0, util.Cell{}, 1, nil},
+			{"glider.rle", 100, 100, util.Cell{X: 10, Y: 10}, 4, moved},
+			{"glider.cells", 100, 100, util.Cell{X: 10, Y: 10}, 4, moved},
+		}
+		for _, engine := range []stubs.Engine{stubs.Stateless, stubs.Stateful} {
+			for _, test := range tests {
+				p := gol.Params{
+					Turns:          test.turns,
+					Threads:        4,
+					ImageWidth:     test.width,
+					ImageHeight:    test.height,
+					Engine:         engine,
+					InputFile:      filepath.Join(dir, test.file),
+					Offset:         test.offset,
+					OutputTemplate: filepath.Join(dir, "{name}x{turn}"),
+				}
+				t.Run(fmt.Sprintf("%s-%dx%dx%d-%s", test.file, test.width, test.height, test.turns, engine), func(t *testing.T) {
+					events := make(chan gol.Event)
+					go gol.Run(p, events, nil)
+					var final []util.Cell
+					finished := false
+					for event := range events {
+						switch e := event.(type) {
+						case gol.FinalTurnComplete:
+							final = e.Alive
+							finished = true
+						case gol.IOError:
+							t.Fatalf("Error reading or writing %s: %v", e.Filename, e.Err)
+						}
+					}
+					if !finished {
+						t.Fatal("The game didn't finish")
+					}
+					if p.ImageWidth == 0 {
+						p.ImageWidth, p.ImageHeight = 3, 3
+					}
+					assertEqualBoard(t, final, test.expected, p)
+				})
+			}
+		}
+
+		// The game doesn't start if an empty pattern has no size of its own,
+		// or if the board is a different size to the file without an offset or cropping
+		failures := []gol.Params{
+			{InputFile: filepath.Join(dir, "empty.lif")},
+			{InputFile: filepath.Join(dir, "glider.rle"), ImageWidth: 100, ImageHeight: 100},
+			{InputFile: filepath.Join(dir, "glider.cells"), ImageWidth: 2, ImageHeight: 2, Offset: util.Cell{X: 1, Y: 1}},
+		}
+		for _, p := range failures {
+			p.Turns, p.Threads = 1, 4
+			events := make(chan gol.Event)
+			go gol.Run(p, events, nil)
+			failed := false
+			for event := range events {
+				switch event.(type) {
+				case gol.IOError:
+					failed = true
+				case gol.FinalTurnComplete:
+					t.Errorf("Expected a game from %s at %dx%d not to start", filepath.Base(p.InputFile), p.ImageWidth, p.ImageHeight)
+				}
+			}
+			if !failed {
+				t.Errorf("Expected an IOError for %s at %dx%d", filepath.Base(p.InputFile), p.ImageWidth, p.ImageHeight)
+			}
+		}
+	}
+}
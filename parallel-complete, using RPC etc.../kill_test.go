@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/rpc"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestKillWithOtherGames pauses a 512x512 game while a second game presses k,
+// then checks the workers and server kept running so the first game still reaches turn 100.
+func TestKillWithOtherGames(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 8}
+		expectedAlive := readAliveCells("check/images/512x512x100.pgm", p.ImageWidth, p.ImageHeight)
+
+		events := make(chan gol.Event)
+		keyPresses := make(chan rune, 10)
+		go gol.Run(p, events, keyPresses)
+		keyPresses <- 'p'
+		paused := make(chan bool, 1)
+		final := make(chan []util.Cell, 1)
+		go func() {
+			for event := range events {
+				switch e := event.(type) {
+				case gol.StateChange:
+					if e.NewState == stubs.Paused {
+						paused <- true
+					}
+				case gol.FinalTurnComplete:
+					final <- e.Alive
+				}
+			}
+		}()
+		<-paused
+
+		killer := p
+		killer.Turns = 1000000
+		killer.Port = "8055"
+		killerEvents := make(chan gol.Event)
+		killerKeys := make(chan rune, 1)
+		go gol.Run(killer, killerEvents, killerKeys)
+		killerKeys <- 'k'
+		killed := false
+		for event := range killerEvents {
+			if _, ok := event.(gol.FinalTurnComplete); ok {
+				killed = true
+			}
+		}
+		if !killed {
+			t.Fatal("Expected the game pressing k to end")
+		}
+
+		server, err := rpc.Dial("tcp", "localhost:8020")
+		if err != nil {
+			t.Fatal("Expected the server to keep running:", err)
+		}
+		defer server.Close()
+		status := new(stubs.StatusResponse)
+		err = server.Call(stubs.ServerStatus, stubs.Empty{}, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status.Workers) == 0 {
+			t.Fatal("Expected the workers to keep running")
+		}
+
+		keyPresses <- 'p'
+		assertEqualBoard(t, <-final, expectedAlive, p)
+	}
+}
@@ -3,10 +3,18 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net"
+	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 
+	"uk.ac.bris.cs/gameoflife/ansi"
+	"uk.ac.bris.cs/gameoflife/control"
 	"uk.ac.bris.cs/gameoflife/gol"
-	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/headless"
+	"uk.ac.bris.cs/gameoflife/render"
+	"uk.ac.bris.cs/gameoflife/stubs"
 )
 
 // main is the function called when starting Game of Life with 'go run .'
@@ -23,14 +31,14 @@ func main() {
 	flag.IntVar(
 		&params.ImageWidth,
 		"w",
-		512,
-		"Specify the width of the image. Defaults to 512.")
+		0,
+		"Specify the width of the image. Defaults to the width of the input file, the game's when resuming or observing, or 512.")
 
 	flag.IntVar(
 		&params.ImageHeight,
 		"h",
-		512,
-		"Specify the height of the image. Defaults to 512.")
+		0,
+		"Specify the height of the image. Defaults to the height of the input file, the game's when resuming or observing, or 512.")
 
 	flag.IntVar(
 		&params.Turns,
@@ -56,33 +64,372 @@ func main() {
 		true,
 		"Specify whether or not to use SDL")
 
+	flag.IntVar(&params.FrameRate,
+		"fps",
+		60,
+		"Specify the most boards the server sends a second for visual updates. Turns in between are skipped so the game runs at full speed. 0 sends every turn. Defaults to 60.")
+
 	flag.BoolVar(&params.ResumeGame,
 		"resume",
 		false,
-		"Disables the SDL window, so there is no visualisation during the tests.")
+		"Resume a previous game on the server instead of starting a new one. A game still running after its controller disconnected is taken over from the turn it has reached.")
+
+	onDisconnect := flag.String(
+		"on-disconnect",
+		"run",
+		"Specify what the server does with the game if this controller disconnects without quitting: run keeps calculating turns until the server's orphan timeout, pause waits for a controller to resume it. Defaults to run.")
+
+	flag.StringVar(&params.GameID,
+		"game",
+		"",
+		"Specify the ID of the game to resume or observe. Defaults to the most recent game, or the only running game when observing.")
+
+	flag.BoolVar(&params.Observe,
+		"observe",
+		false,
+		"Watch a game running on the server without controlling it. The size of the board is taken from the game. Press q to stop watching.")
+
+	rule := flag.String(
+		"rule",
+		"",
+		"Specify the Life-like rule in B/S notation (e.g. B36/S23) or by name (e.g. highlife). Defaults to the rule in the input file, or B3/S23.")
+
+	topology := flag.String(
+		"topology",
+		"torus",
+		"Specify how the board edges are joined: torus, plane, cylinder or klein. Defaults to torus.")
+
+	engine := flag.String(
+		"engine",
+		"stateless",
+		"Specify how the server calculates turns: stateless sends the board to the workers every turn, stateful leaves it on them, hashlife skips through turns on the server, sparse only calculates the parts of the board which change. Defaults to stateless.")
+
+	flag.StringVar(&params.InputFile,
+		"input",
+		"",
+		"Specify a pattern (.rle, .lif or .cells) or image (.pgm or .pbm) file to load. Defaults to images/<w>x<h>.pgm.")
+
+	offset := flag.String(
+		"offset",
+		"0,0",
+		"Specify where the top left of the input pattern is placed on the board as x,y. Defaults to 0,0.")
+
+	flag.BoolVar(&params.Crop,
+		"crop",
+		false,
+		"Let the board be a different size to the input file without an offset, leaving out any alive cells which don't fit. Defaults to false, where a -w or -h different to the file is an error.")
+
+	outputFormat := flag.String(
+		"output-format",
+		"pgm",
+		"Specify the format to save the board in: pgm, pbm, png, rle, life106 or cells. PNG images colour cells by how long they have been alive, which needs every turn to be calculated. Defaults to pgm.")
+
+	flag.Float64Var(&params.Threshold,
+		"threshold",
+		0,
+		"Specify how bright a greyscale pixel must be to be alive, from 0 to 1. Defaults to 0, where any pixel that isn't black is alive.")
+
+	flag.StringVar(&params.OutputTemplate,
+		"output",
+		gol.DefaultOutputTemplate,
+		"Specify the name to save the board as. {name} is replaced by the input file name, {w} and {h} by the board size and {turn} by the turn. Defaults to "+gol.DefaultOutputTemplate+" in out/.")
+
+	flag.IntVar(&params.Trail,
+		"trail",
+		8,
+		"Specify the number of turns dead cells fade out over in PNG images. Defaults to 8.")
+
+	headlessMode := flag.Bool(
+		"headless",
+		false,
+		"Run without SDL, writing every event to stdout as a line of JSON. Turns off visual updates. Keys pressed in the terminal control the game.")
+
+	terminalMode := flag.String(
+		"ansi",
+		"",
+		"Draw the board in the terminal instead of SDL, with halfblock or braille characters. Arrow keys scroll, + and - zoom. Logs are best redirected with 2>file. Defaults to SDL.")
+
+	var animation render.AnimationOptions
+	flag.StringVar(&animation.GIF,
+		"record-gif",
+		"",
+		"Specify a file to record the game to as an animated GIF. Turns on visual updates. Defaults to no recording.")
+
+	flag.StringVar(&animation.PNGDir,
+		"record-png",
+		"",
+		"Specify a directory to record each frame of the game to as a PNG. Turns on visual updates. Defaults to no recording.")
+
+	flag.IntVar(&animation.FirstTurn,
+		"record-from",
+		0,
+		"Specify the first turn to record. Defaults to 0.")
+
+	flag.IntVar(&animation.LastTurn,
+		"record-to",
+		-1,
+		"Specify the last turn to record. Required for GIFs, which are kept in memory until the game ends. Defaults to the end of the game for PNGs.")
+
+	flag.IntVar(&animation.Stride,
+		"record-stride",
+		1,
+		"Specify the number of turns between recorded frames. Defaults to 1.")
+
+	flag.IntVar(&animation.Scale,
+		"record-scale",
+		1,
+		"Specify the size of each cell in the recording in pixels. Defaults to 1.")
+
+	recordFPS := flag.Int(
+		"record-fps",
+		10,
+		"Specify the number of frames per second in the recorded GIF. Defaults to 10.")
+
+	controlSocket := flag.String(
+		"control",
+		"",
+		"Specify a unix socket to create so scripts can send commands (pause, save, quit, kill, randomise, or \"step 50\", \"jump 100\", \"speed 10\") to the game. Defaults to no socket.")
 
 	flag.Parse()
 
-	fmt.Println("Threads:", params.Threads)
-	fmt.Println("Width:", params.ImageWidth)
-	fmt.Println("Height:", params.ImageHeight)
-	fmt.Println("Server:", params.ServerAddress)
-	fmt.Println("RPC Port:", params.Port)
+	// In headless mode stdout is only for events, so everything else goes to stderr
+	info := os.Stdout
+	if *headlessMode {
+		info = os.Stderr
+		params.VisualUpdates = false
+	}
+
+	var err error
+	var mode ansi.Mode
+	if *terminalMode != "" {
+		mode, err = ansi.ParseMode(*terminalMode)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid terminal mode:", err)
+			os.Exit(1)
+		}
+	}
+	if *rule != "" {
+		params.Rule, err = stubs.ParseRule(*rule)
+		params.RuleSet = true
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid rule:", err)
+			os.Exit(1)
+		}
+	}
+	_, err = fmt.Sscanf(*offset, "%d,%d", &params.Offset.X, &params.Offset.Y)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid offset:", err)
+		os.Exit(1)
+	}
+	params.OutputFormat, err = gol.ParseFileFormat(*outputFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid output format:", err)
+		os.Exit(1)
+	}
+	if params.ResumeGame || params.Observe {
+		// A resumed or observed board is the size of the game on the server, which checks -w and -h if they are given
+	} else if params.InputFile == "" {
+		if params.ImageWidth == 0 {
+			params.ImageWidth = 512
+		}
+		if params.ImageHeight == 0 {
+			params.ImageHeight = 512
+		}
+	} else {
+		params.ImageWidth, params.ImageHeight, err = gol.BoardSize(params)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading input:", err)
+			os.Exit(1)
+		}
+	}
+	params.Topology, err = stubs.ParseTopology(*topology)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid topology:", err)
+		os.Exit(1)
+	}
+	params.Engine, err = stubs.ParseEngine(*engine)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid engine:", err)
+		os.Exit(1)
+	}
+	params.OnDisconnect, err = stubs.ParseDisconnectPolicy(*onDisconnect)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid disconnect policy:", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(info, "Threads:", params.Threads)
+	fmt.Fprintln(info, "Server:", params.ServerAddress)
+	fmt.Fprintln(info, "RPC Port:", params.Port)
+	if params.RuleSet {
+		fmt.Fprintln(info, "Rule:", params.Rule)
+	}
+	if params.InputFile != "" {
+		fmt.Fprintln(info, "Input:", params.InputFile)
+	}
+	fmt.Fprintln(info, "Topology:", params.Topology)
+	fmt.Fprintln(info, "Engine:", params.Engine)
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
 
+	// The recording needs visual updates for every turn, not just the ones the display has time for
+	// The display is only shown the visual updates if it wanted them
+	recording := animation.GIF != "" || animation.PNGDir != ""
+	visual := params.VisualUpdates
+	if recording {
+		if *recordFPS <= 0 {
+			fmt.Fprintln(os.Stderr, "Invalid recording frame rate:", *recordFPS)
+			os.Exit(1)
+		}
+		animation.Delay = 100 / *recordFPS
+		params.VisualUpdates = true
+		params.FrameRate = 0
+	}
+
+	// A resumed or observed game is started before anything is drawn, as the board is the size of the game on the server
+	var display <-chan gol.Event = events
+	running := false
+	if (params.ResumeGame || params.Observe) && (params.ImageWidth == 0 || params.ImageHeight == 0) {
+		var started gol.GameStarted
+		var ok bool
+		started, display, ok = startGame(params, events, keyPresses)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Error: the game couldn't be started on the server")
+			os.Exit(1)
+		}
+		params.ImageWidth, params.ImageHeight = started.Width, started.Height
+		running = true
+	}
+	fmt.Fprintln(info, "Width:", params.ImageWidth)
+	fmt.Fprintln(info, "Height:", params.ImageHeight)
+
+	// The recording sees every event first, with the visual updates it needs hidden from the display if it didn't want them
+	var recorder *render.Animation
+	if recording {
+		recorder, err = render.NewAnimation(params.ImageWidth, params.ImageHeight, animation)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error starting recording:", err)
+			os.Exit(1)
+		}
+		display = render.Tee(recorder, display, visual)
+	}
+
+	var listener net.Listener
+	if *controlSocket != "" {
+		listener, err = control.ListenSocket(*controlSocket, keyPresses)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error starting control socket:", err)
+			os.Exit(1)
+		}
+	}
+
+	exitCode := 0
+	if *headlessMode {
+		// Without SDL the keys come from the terminal instead
+		restore, err := control.ReadTerminal(keyPresses)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading keys from the terminal:", err)
+			os.Exit(1)
+		}
+		restoreOnInterrupt(restore, listener)
+
+		if !running {
+			go gol.Run(params, events, keyPresses)
+		}
+		err = headless.Run(display, os.Stdout)
+		restore()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			exitCode = 1
+		}
+	} else {
+		var renderer render.Renderer
+		restore := func() {}
+		if *terminalMode != "" {
+			keys := make(chan rune, 10)
+			restore, err = control.ReadTerminalKeys(keys)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error reading keys from the terminal:", err)
+				os.Exit(1)
+			}
+			terminal := ansi.NewRenderer(os.Stdout, params.ImageWidth, params.ImageHeight, mode, keys)
+			terminal.Start()
+			restoreOnInterrupt(func() {
+				terminal.Close()
+				restore()
+			}, listener)
+			renderer = terminal
+		} else {
+			renderer, err = render.NewSDL(params)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		if !running {
+			go gol.Run(params, events, keyPresses)
+		}
+		render.Run(renderer, display, keyPresses)
+		err = renderer.Close()
+		restore()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error drawing the game:", err)
+			exitCode = 1
+		}
+	}
+
+	if recorder != nil {
+		err = recorder.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error recording:", err)
+			exitCode = 1
+		} else {
+			fmt.Fprintln(info, "Recorded", recorder.Frames(), "frames")
+		}
+	}
+
+	// Closing the listener removes the socket file
+	if listener != nil {
+		listener.Close()
+	}
+	os.Exit(exitCode)
+}
+
+// Start the game and wait for the server to start it, so the size of a resumed or observed board is known
+// The events are passed on from the returned channel, starting with the GameStarted event
+// If the game couldn't be started the events channel is closed without a GameStarted event, and false is returned
+func startGame(params gol.Params, events chan gol.Event, keyPresses <-chan rune) (gol.GameStarted, <-chan gol.Event, bool) {
 	go gol.Run(params, events, keyPresses)
-	//if !(*noVis) {
-	sdl.Run(params, events, keyPresses)
-	//} else {
-	//	complete := false
-	//	for !complete {
-	//		event := <-events
-	//		switch event.(type) {
-	//		case gol.FinalTurnComplete:
-	//			complete = true
-	//		}
-	//	}
-	//}
+
+	out := make(chan gol.Event, cap(events)+1)
+	for event := range events {
+		out <- event
+		if started, ok := event.(gol.GameStarted); ok {
+			go func() {
+				for event := range events {
+					out <- event
+				}
+				close(out)
+			}()
+			return started, out, true
+		}
+	}
+	close(out)
+	return gol.GameStarted{}, out, false
+}
+
+// Put the terminal back if we are interrupted, as it won't echo keys otherwise
+func restoreOnInterrupt(restore func(), listener net.Listener) {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		restore()
+		if listener != nil {
+			listener.Close()
+		}
+		os.Exit(1)
+	}()
 }
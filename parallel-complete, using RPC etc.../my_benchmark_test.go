@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"os"
 	"testing"
+
+	"uk.ac.bris.cs/gameoflife/engine"
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 const benchLength = 1000
@@ -29,4 +33,49 @@ func BenchmarkGol(b *testing.B) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// BenchmarkKernel compares the packed 64 bit kernel used by the workers
+// with the old kernel which calculates one cell at a time
+func BenchmarkKernel(b *testing.B) {
+	const size = 512
+	board := make([][]bool, size)
+	for row := range board {
+		board[row] = make([]bool, size)
+	}
+	alive, err := util.ReadAliveCells(fmt.Sprintf("images/%dx%d.pgm", size, size), size, size)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, cell := range alive {
+		board[cell.Y][cell.X] = true
+	}
+
+	// A halo of the whole board, with the rows wrapped around the top and bottom
+	cells := append([][]bool{board[size-1]}, board...)
+	cells = append(cells, board[0])
+	halo := stubs.Halo{
+		BitBoard: stubs.BitBoardFromSlice(cells, size+2, size),
+		Offset:   1,
+		StartPtr: 0,
+		EndPtr:   size,
+	}
+
+	kernels := []struct {
+		name   string
+		doTurn func(stubs.Halo, int, stubs.Rule, stubs.Topology) stubs.Fragment
+	}{
+		{"ByCell", engine.DoTurnByCell},
+		{"Packed", engine.DoTurn},
+	}
+	for _, kernel := range kernels {
+		for _, threads := range []int{1, 4, 8} {
+			name := fmt.Sprintf("%s-%dx%d-%d", kernel.name, size, size, threads)
+			b.Run(name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					kernel.doTurn(halo, threads, stubs.ConwayRule, stubs.Torus)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/rpc"
+	"testing"
+	"time"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestObserve pauses a 512x512 game to attach two observers, detaches one, then checks the other sees the same 100 turns as the controller.
+func TestObserve(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 8}
+		expectedAlive := readAliveCells("check/images/512x512x100.pgm", p.ImageWidth, p.ImageHeight)
+
+		server, err := rpc.Dial("tcp", "localhost:8020")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+		// Other games can be running on the server, so the observers are given the ID of ours
+		previous := gameIDs(t, server)
+
+		events := make(chan gol.Event)
+		keyPresses := make(chan rune, 10)
+		go gol.Run(p, events, keyPresses)
+		keyPresses <- 'p'
+		paused := make(chan bool, 1)
+		final := make(chan []util.Cell, 1)
+		go func() {
+			for event := range events {
+				switch e := event.(type) {
+				case gol.StateChange:
+					if e.NewState == stubs.Paused {
+						paused <- true
+					}
+				case gol.FinalTurnComplete:
+					final <- e.Alive
+				}
+			}
+		}()
+		<-paused
+		gameID := newGameID(t, server, previous)
+
+		// The watcher draws the game, the leaver detaches while it is paused
+		watcher := p
+		watcher.Observe = true
+		watcher.GameID = gameID
+		watcher.VisualUpdates = true
+		watcher.Port = "8051"
+		watcherEvents := make(chan gol.Event)
+		go gol.Run(watcher, watcherEvents, nil)
+		waitForObservers(t, server, gameID, 1)
+
+		leaver := p
+		leaver.Observe = true
+		leaver.GameID = gameID
+		leaver.Port = "8052"
+		leaverEvents := make(chan gol.Event)
+		leaverKeys := make(chan rune, 1)
+		go gol.Run(leaver, leaverEvents, leaverKeys)
+		waitForObservers(t, server, gameID, 2)
+		leaverKeys <- 'q'
+		for range leaverEvents {
+		}
+		waitForObservers(t, server, gameID, 1)
+
+		keyPresses <- 'p'
+
+		board := make([][]bool, p.ImageHeight)
+		for y := range board {
+			board[y] = make([]bool, p.ImageWidth)
+		}
+		var watched []util.Cell
+		for event := range watcherEvents {
+			switch e := event.(type) {
+			case gol.CellFlipped:
+				board[e.Cell.Y][e.Cell.X] = !board[e.Cell.Y][e.Cell.X]
+			case gol.FinalTurnComplete:
+				watched = e.Alive
+			}
+		}
+
+		var drawn []util.Cell
+		for y := range board {
+			for x := range board[y] {
+				if board[y][x] {
+					drawn = append(drawn, util.Cell{X: x, Y: y})
+				}
+			}
+		}
+		if !assertEqualBoard(t, watched, expectedAlive, p) {
+			return
+		}
+		if !assertEqualBoard(t, drawn, expectedAlive, p) {
+			return
+		}
+		assertEqualBoard(t, <-final, expectedAlive, p)
+	}
+}
+
+// Get the status of every game on the server
+func gameStatus(t *testing.T, server *rpc.Client) []stubs.GameStatus {
+	status := new(stubs.StatusResponse)
+	err := server.Call(stubs.ServerStatus, stubs.Empty{}, status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status.Games
+}
+
+// Get the IDs of the games on the server
+func gameIDs(t *testing.T, server *rpc.Client) map[string]bool {
+	ids := make(map[string]bool)
+	for _, game := range gameStatus(t, server) {
+		ids[game.GameID] = true
+	}
+	return ids
+}
+
+// Get the ID of the running game which wasn't on the server before, failing if there isn't exactly one
+func newGameID(t *testing.T, server *rpc.Client, previous map[string]bool) string {
+	var started []string
+	for _, game := range gameStatus(t, server) {
+		if game.Running && !previous[game.GameID] {
+			started = append(started, game.GameID)
+		}
+	}
+	if len(started) != 1 {
+		t.Fatalf("Expected one new game on the server, got %v", started)
+	}
+	return started[0]
+}
+
+// Wait for a running game on the server to have n observers
+func waitForObservers(t *testing.T, server *rpc.Client, gameID string, n int) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		for _, game := range gameStatus(t, server) {
+			if game.GameID == gameID && game.Running && game.Observers == n {
+				return
+			}
+		}
+	}
+	t.Fatalf("Timed out waiting for game %s to have %d observers", gameID, n)
+}
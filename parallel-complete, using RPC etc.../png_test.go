@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Palette indexes of png images, see gol/png.go
+const (
+	pngNewborn    = 1
+	pngLastAlive  = 128
+	pngFirstTrail = 129
+	pngLastTrail  = 192
+)
+
+// readPng reads the palette index of each pixel of a png image saved by the game
+func readPng(t *testing.T, path string) *image.Paletted {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paletted, ok := decoded.(*image.Paletted)
+	if !ok {
+		t.Fatalf("Expected a paletted image, got %T", decoded)
+	}
+	return paletted
+}
+
+// TestPng tests 16x16 and 64x64 png output files on 0, 1 and 100 turns with a trail of 8 turns.
+// Alive cells must have alive colours, which are all white on turn 0, and dead cells must be black or trail colours.
+func TestPng(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		tests := []gol.Params{
+			{ImageWidth: 16, ImageHeight: 16},
+			{ImageWidth: 64, ImageHeight: 64},
+		}
+		for _, p := range tests {
+			p.Threads = 8
+			p.OutputFormat = gol.PNG
+			p.Trail = 8
+			for _, turns := range []int{0, 1, 100} {
+				p.Turns = turns
+				expectedAlive := readAliveCells(
+					"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+				testName := fmt.Sprintf("%dx%dx%d", p.ImageWidth, p.ImageHeight, p.Turns)
+				t.Run(testName, func(t *testing.T) {
+					events := make(chan gol.Event)
+					go gol.Run(p, events, nil)
+					for range events {
+					}
+
+					img := readPng(t, "out/"+fmt.Sprintf("%vx%vx%v.png", p.ImageWidth, p.ImageHeight, turns))
+					var cellsFromImage []util.Cell
+					trails := 0
+					for y := 0; y < p.ImageHeight; y++ {
+						for x := 0; x < p.ImageWidth; x++ {
+							index := img.ColorIndexAt(x, y)
+							switch {
+							case index >= pngNewborn && index <= pngLastAlive:
+								cellsFromImage = append(cellsFromImage, util.Cell{X: x, Y: y})
+								if turns == 0 && index != pngNewborn {
+									t.Errorf("Expected every cell to be newborn on turn 0, (%d, %d) has colour %d", x, y, index)
+								}
+							case index >= pngFirstTrail && index <= pngLastTrail:
+								trails++
+							case index != 0:
+								t.Errorf("Unexpected colour %d at (%d, %d)", index, x, y)
+							}
+						}
+					}
+					assertEqualBoard(t, cellsFromImage, expectedAlive, p)
+					if turns > 0 && trails == 0 {
+						t.Error("Expected cells which died in the last 8 turns to leave a trail")
+					}
+				})
+			}
+		}
+	}
+}
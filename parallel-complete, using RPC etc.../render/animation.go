@@ -0,0 +1,205 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// AnimationOptions chooses the turns recorded in an animation and how they are drawn
+type AnimationOptions struct {
+	// Turns from FirstTurn to LastTurn are recorded, a negative LastTurn records until the game ends
+	// GIFs must have a LastTurn, as their frames are kept in memory until the game ends
+	FirstTurn int
+	LastTurn  int
+	// Stride is the number of turns between frames
+	Stride int
+	// Scale is the width of each cell in pixels
+	Scale int
+	// Delay is how long each frame of the GIF is shown for, in hundredths of a second
+	Delay int
+
+	// GIF is the file to write the animation to, if it isn't empty
+	GIF string
+	// PNGDir is a directory to write each frame to as a PNG named after its turn, if it isn't empty
+	PNGDir string
+}
+
+// GIFs can be at most 65535 pixels across
+const maxGIFSize = 65535
+
+// The most memory the frames of a GIF can take, at one byte per pixel
+const maxGIFMemory = 1 << 30
+
+// Alive cells are white, as in the PGM images
+var animationPalette = color.Palette{color.Black, color.White}
+
+// Animation is a renderer which records the board on a range of turns as an animated GIF or a sequence of PNGs
+// It needs the CellFlipped and TurnComplete events, so the game must be run with visual updates
+// GIF frames are kept in memory until Close, PNGs are written as soon as each turn completes
+type Animation struct {
+	options AnimationOptions
+	width   int
+	height  int
+	board   [][]bool
+
+	frames   []*image.Paletted
+	delays   []int
+	recorded int
+	lastTurn int
+	err      error
+}
+
+// NewAnimation creates a renderer recording a width x height board
+func NewAnimation(width, height int, options AnimationOptions) (*Animation, error) {
+	if options.GIF == "" && options.PNGDir == "" {
+		return nil, errors.New("animation needs a GIF file or a PNG directory")
+	}
+	if options.Stride <= 0 || options.Scale <= 0 {
+		return nil, errors.New("animation stride and scale must be at least 1")
+	}
+	if options.LastTurn >= 0 && options.LastTurn < options.FirstTurn {
+		return nil, fmt.Errorf("animation ends at turn %d, before it starts at turn %d", options.LastTurn, options.FirstTurn)
+	}
+	if options.GIF != "" {
+		if width*options.Scale > maxGIFSize || height*options.Scale > maxGIFSize {
+			return nil, fmt.Errorf("%dx%d board is too big for a GIF at scale %d", width, height, options.Scale)
+		}
+		if options.LastTurn < 0 {
+			return nil, errors.New("a GIF needs a last turn to record, as every frame is kept in memory until the game ends")
+		}
+		frames := (options.LastTurn-options.FirstTurn)/options.Stride + 1
+		frameSize := width * options.Scale * height * options.Scale
+		if frameSize > 0 && frames > maxGIFMemory/frameSize {
+			return nil, fmt.Errorf("%d frames of a %dx%d board at scale %d would take more than %d MB, record fewer turns or use a bigger stride",
+				frames, width, height, options.Scale, maxGIFMemory>>20)
+		}
+	}
+	if options.PNGDir != "" {
+		err := os.MkdirAll(options.PNGDir, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	board := make([][]bool, height)
+	for y := range board {
+		board[y] = make([]bool, width)
+	}
+	return &Animation{
+		options:  options,
+		width:    width,
+		height:   height,
+		board:    board,
+		lastTurn: -1,
+	}, nil
+}
+
+func (a *Animation) HandleEvent(event gol.Event) {
+	switch e := event.(type) {
+	case gol.CellFlipped:
+		if e.Cell.X >= 0 && e.Cell.X < a.width && e.Cell.Y >= 0 && e.Cell.Y < a.height {
+			a.board[e.Cell.Y][e.Cell.X] = !a.board[e.Cell.Y][e.Cell.X]
+		}
+	case gol.TurnComplete:
+		if a.err == nil && a.records(e.CompletedTurns) {
+			a.err = a.addFrame(e.CompletedTurns)
+		}
+	}
+}
+
+// Keys returns a nil channel, as nothing can be pressed
+func (a *Animation) Keys() <-chan rune {
+	return nil
+}
+
+// Close writes the GIF, returning the first error from recording
+func (a *Animation) Close() error {
+	if a.err != nil || a.options.GIF == "" {
+		return a.err
+	}
+	if a.recorded == 0 {
+		return errors.New("no turns were recorded for " + a.options.GIF)
+	}
+
+	file, err := os.Create(a.options.GIF)
+	if err != nil {
+		return err
+	}
+	err = gif.EncodeAll(file, &gif.GIF{Image: a.frames, Delay: a.delays})
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Frames returns the number of frames recorded so far
+func (a *Animation) Frames() int {
+	return a.recorded
+}
+
+// Work out if a turn is one of the frames
+// A turn can be reported more than once, e.g. when a game is resumed, but is only recorded once
+func (a *Animation) records(turn int) bool {
+	if turn <= a.lastTurn || turn < a.options.FirstTurn {
+		return false
+	}
+	if a.options.LastTurn >= 0 && turn > a.options.LastTurn {
+		return false
+	}
+	return (turn-a.options.FirstTurn)%a.options.Stride == 0
+}
+
+// Draw the board as a frame, and keep it for the GIF or write it as a PNG
+func (a *Animation) addFrame(turn int) error {
+	a.lastTurn = turn
+	scale := a.options.Scale
+	frame := image.NewPaletted(image.Rect(0, 0, a.width*scale, a.height*scale), animationPalette)
+	for y, row := range a.board {
+		for x, alive := range row {
+			if !alive {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				start := frame.PixOffset(x*scale, y*scale+dy)
+				for dx := 0; dx < scale; dx++ {
+					frame.Pix[start+dx] = 1
+				}
+			}
+		}
+	}
+
+	if a.options.PNGDir != "" {
+		err := writePNG(filepath.Join(a.options.PNGDir, fmt.Sprintf("%08d.png", turn)), frame)
+		if err != nil {
+			return err
+		}
+	}
+	if a.options.GIF != "" {
+		a.frames = append(a.frames, frame)
+		a.delays = append(a.delays, a.options.Delay)
+	}
+	a.recorded++
+	return nil
+}
+
+func writePNG(filename string, frame image.Image) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, frame)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewAnimation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-animation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	gif := filepath.Join(dir, "game.gif")
+	pngs := filepath.Join(dir, "frames")
+
+	tests := []struct {
+		name          string
+		width, height int
+		options       AnimationOptions
+		ok            bool
+	}{
+		{"gif", 512, 512, AnimationOptions{LastTurn: 100, Stride: 1, Scale: 1, GIF: gif}, true},
+		{"pngs until the end", 512, 512, AnimationOptions{LastTurn: -1, Stride: 1, Scale: 1, PNGDir: pngs}, true},
+		{"nowhere to record", 16, 16, AnimationOptions{LastTurn: 10, Stride: 1, Scale: 1}, false},
+		{"no stride", 16, 16, AnimationOptions{LastTurn: 10, Stride: 0, Scale: 1, GIF: gif}, false},
+		{"no scale", 16, 16, AnimationOptions{LastTurn: 10, Stride: 1, Scale: 0, GIF: gif}, false},
+		{"ends before it starts", 16, 16, AnimationOptions{FirstTurn: 10, LastTurn: 5, Stride: 1, Scale: 1, GIF: gif}, false},
+		{"too wide for a gif", 5000, 16, AnimationOptions{LastTurn: 10, Stride: 1, Scale: 20, GIF: gif}, false},
+		// Frames are kept until the game ends, so a GIF can't record a game which might never end
+		{"gif until the end", 16, 16, AnimationOptions{LastTurn: -1, Stride: 1, Scale: 1, GIF: gif}, false},
+		{"gif and pngs until the end", 16, 16, AnimationOptions{LastTurn: -1, Stride: 1, Scale: 1, GIF: gif, PNGDir: pngs}, false},
+		// 10000 frames of 512x512 would be 2.5 GB
+		{"gif too long", 512, 512, AnimationOptions{LastTurn: 9999, Stride: 1, Scale: 1, GIF: gif}, false},
+		{"gif with a stride", 512, 512, AnimationOptions{LastTurn: 9999, Stride: 10, Scale: 1, GIF: gif}, true},
+		{"gif too big a scale", 512, 512, AnimationOptions{LastTurn: 999, Stride: 1, Scale: 4, GIF: gif}, false},
+	}
+	for _, test := range tests {
+		_, err := NewAnimation(test.width, test.height, test.options)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: expected ok to be %v, got %v", test.name, test.ok, err)
+		}
+	}
+}
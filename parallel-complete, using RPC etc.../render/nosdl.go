@@ -0,0 +1,15 @@
+//go:build nosdl
+// +build nosdl
+
+package render
+
+import (
+	"errors"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// NewSDL fails, as the game was built with the nosdl tag
+func NewSDL(p gol.Params) (Renderer, error) {
+	return nil, errors.New("built without SDL (the nosdl build tag), use -ansi or -headless instead")
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// Nop is a renderer which shows nothing and never has any keys pressed
+type Nop struct{}
+
+func (Nop) HandleEvent(event gol.Event) {}
+
+// Keys returns a nil channel, which is never ready
+func (Nop) Keys() <-chan rune {
+	return nil
+}
+
+func (Nop) Close() error {
+	return nil
+}
+
+// Recorder is a renderer which keeps every event it is given, so tests can check what would have been shown
+// Keys can be pressed with Press
+type Recorder struct {
+	mutex  sync.Mutex
+	events []gol.Event
+	closed bool
+	keys   chan rune
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{keys: make(chan rune, 10)}
+}
+
+func (r *Recorder) HandleEvent(event gol.Event) {
+	r.mutex.Lock()
+	r.events = append(r.events, event)
+	r.mutex.Unlock()
+}
+
+func (r *Recorder) Keys() <-chan rune {
+	return r.keys
+}
+
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	r.closed = true
+	r.mutex.Unlock()
+	return nil
+}
+
+// Press sends a key to the game as if the user had pressed it
+func (r *Recorder) Press(key rune) {
+	r.keys <- key
+}
+
+// Events returns a copy of the events recorded so far
+func (r *Recorder) Events() []gol.Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	events := make([]gol.Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+// Closed reports whether Close has been called
+func (r *Recorder) Closed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.closed
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// This package connects the game to whatever is showing it to the user
+// SDL is only built in without the nosdl build tag, so the rest of the game builds on machines without SDL
+
+// Renderer shows the game's events to the user, and collects the keys they press
+type Renderer interface {
+	// HandleEvent shows an event from the game
+	HandleEvent(event gol.Event)
+	// Keys gives the keys pressed by the user, which are passed on to the game
+	Keys() <-chan rune
+	// Close frees anything the renderer holds once the game has finished, like its window
+	Close() error
+}
+
+// Poller is implemented by renderers which need to do work regularly on the goroutine running them,
+// e.g. SDL can only be asked for keys on the thread which created its window
+type Poller interface {
+	Poll()
+}
+
+// pollInterval is the time between calls to a Poller
+const pollInterval = 5 * time.Millisecond
+
+// Run passes the game's events to the renderer and its keys to the game until the events channel is closed
+// The renderer isn't closed, so it can be closed by the caller after checking for errors
+func Run(r Renderer, events <-chan gol.Event, keyPresses chan<- rune) {
+	poller, isPoller := r.(Poller)
+	var poll <-chan time.Time
+	if isPoller {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	keys := r.Keys()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			r.HandleEvent(event)
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			// The game stops reading keys once it has finished, so drop them rather than waiting forever
+			select {
+			case keyPresses <- key:
+			default:
+			}
+		case <-poll:
+			poller.Poll()
+		}
+	}
+}
+
+// Tee passes every event to r as well as passing it on to the channel returned, which is closed after events
+// CellFlipped and TurnComplete events are only passed on if visual is set, so r can use them without the display seeing them
+// r isn't closed, but it is safe to close it once the returned channel is closed
+func Tee(r Renderer, events <-chan gol.Event, visual bool) <-chan gol.Event {
+	out := make(chan gol.Event, cap(events))
+	go func() {
+		for event := range events {
+			r.HandleEvent(event)
+			switch event.(type) {
+			case gol.CellFlipped, gol.TurnComplete:
+				if !visual {
+					continue
+				}
+			}
+			out <- event
+		}
+		close(out)
+	}()
+	return out
+}
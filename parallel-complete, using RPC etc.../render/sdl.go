@@ -0,0 +1,15 @@
+//go:build !nosdl
+// +build !nosdl
+
+package render
+
+import (
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/sdl"
+)
+
+// NewSDL opens an SDL window to draw the game in
+// It must be called from the main thread, which must stay locked to it
+func NewSDL(p gol.Params) (Renderer, error) {
+	return sdl.NewRenderer(p), nil
+}
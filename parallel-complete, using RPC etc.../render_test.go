@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/render"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRender checks the board drawn from the CellFlipped events matches the final board, using a renderer which records the events.
+func TestRender(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		tests := []gol.Params{
+			{ImageWidth: 16, ImageHeight: 16},
+			{ImageWidth: 64, ImageHeight: 64},
+		}
+		for _, p := range tests {
+			p.Threads = 8
+			p.VisualUpdates = true
+			for _, turns := range []int{0, 1, 100} {
+				p.Turns = turns
+				expectedAlive := readAliveCells(
+					"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+				testName := fmt.Sprintf("%dx%dx%d", p.ImageWidth, p.ImageHeight, p.Turns)
+				t.Run(testName, func(t *testing.T) {
+					events := make(chan gol.Event)
+					keyPresses := make(chan rune, 10)
+					recorder := render.NewRecorder()
+					go gol.Run(p, events, keyPresses)
+					render.Run(recorder, events, keyPresses)
+
+					board := make([][]bool, p.ImageHeight)
+					for y := range board {
+						board[y] = make([]bool, p.ImageWidth)
+					}
+					var final []util.Cell
+					for _, event := range recorder.Events() {
+						switch e := event.(type) {
+						case gol.CellFlipped:
+							board[e.Cell.Y][e.Cell.X] = !board[e.Cell.Y][e.Cell.X]
+						case gol.FinalTurnComplete:
+							final = e.Alive
+						}
+					}
+
+					var drawn []util.Cell
+					for y := range board {
+						for x := range board[y] {
+							if board[y][x] {
+								drawn = append(drawn, util.Cell{X: x, Y: y})
+							}
+						}
+					}
+					if !assertEqualBoard(t, final, expectedAlive, p) {
+						return
+					}
+					assertEqualBoard(t, drawn, expectedAlive, p)
+				})
+			}
+		}
+		util.Status = true
+	}
+}
+
+// TestAnimation records 16x16 and 64x64 games as GIFs, checking the frames for turns 0, 1 and 100.
+func TestAnimation(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		dir, err := ioutil.TempDir("", "gol-animation")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		tests := []gol.Params{
+			{ImageWidth: 16, ImageHeight: 16},
+			{ImageWidth: 64, ImageHeight: 64},
+		}
+		for _, p := range tests {
+			p.Threads = 8
+			p.Turns = 100
+			p.VisualUpdates = true
+			testName := fmt.Sprintf("%dx%d", p.ImageWidth, p.ImageHeight)
+			t.Run(testName, func(t *testing.T) {
+				// Every turn is recorded, then the frames for the turns with check images are compared
+				options := render.AnimationOptions{
+					LastTurn: p.Turns,
+					Stride:   1,
+					Scale:    2,
+					GIF:      filepath.Join(dir, testName+".gif"),
+				}
+				animation, err := render.NewAnimation(p.ImageWidth, p.ImageHeight, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				for range render.Tee(animation, events, false) {
+				}
+				err = animation.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				file, err := os.Open(options.GIF)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer file.Close()
+				recording, err := gif.DecodeAll(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(recording.Image) != p.Turns+1 {
+					t.Fatalf("Expected %d frames, got %d", p.Turns+1, len(recording.Image))
+				}
+
+				for _, turn := range []int{0, 1, 100} {
+					expectedAlive := readAliveCells(
+						"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turn),
+						p.ImageWidth,
+						p.ImageHeight,
+					)
+					var frameAlive []util.Cell
+					frame := recording.Image[turn]
+					for y := 0; y < p.ImageHeight; y++ {
+						for x := 0; x < p.ImageWidth; x++ {
+							if frame.ColorIndexAt(x*options.Scale, y*options.Scale) != 0 {
+								frameAlive = append(frameAlive, util.Cell{X: x, Y: y})
+							}
+						}
+					}
+					p.Turns = turn
+					assertEqualBoard(t, frameAlive, expectedAlive, p)
+				}
+			})
+		}
+		util.Status = true
+	}
+}
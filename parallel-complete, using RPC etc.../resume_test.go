@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net"
+	"net/rpc"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// droppingController is a controller which disconnects without quitting once the game reaches a turn
+type droppingController struct {
+	dropTurn int
+	conns    chan net.Conn
+	dropped  chan bool
+}
+
+func (c *droppingController) TurnComplete(req stubs.BoardStateReport, res *stubs.Empty) error {
+	if req.CompletedTurns >= c.dropTurn {
+		conn := <-c.conns
+		conn.Close()
+		close(c.dropped)
+	}
+	return nil
+}
+
+func (c *droppingController) ReportAliveCells(req stubs.AliveCellsReport, res *stubs.Empty) error {
+	return nil
+}
+
+// TestResume starts a 512x512 game which pauses when its controller disconnects at turn 30, then checks a new controller can take it over to turn 100.
+// The new controller isn't given the size of the board, so it must be told it by the server.
+func TestResume(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 8}
+		board := make([][]bool, p.ImageHeight)
+		for y := range board {
+			board[y] = make([]bool, p.ImageWidth)
+		}
+		for _, cell := range readAliveCells("images/512x512.pgm", p.ImageWidth, p.ImageHeight) {
+			board[cell.Y][cell.X] = true
+		}
+		expectedAlive := readAliveCells("check/images/512x512x100.pgm", p.ImageWidth, p.ImageHeight)
+
+		// Every turn is sent to the first controller, so the game can't get past the turn it disconnects on
+		dropping := &droppingController{dropTurn: 30, conns: make(chan net.Conn, 1), dropped: make(chan bool)}
+		controllerRPC := rpc.NewServer()
+		controllerRPC.RegisterName("Controller", dropping)
+		listener, err := net.Listen("tcp", "localhost:8053")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			dropping.conns <- conn
+			controllerRPC.ServeConn(conn)
+		}()
+
+		server, err := rpc.Dial("tcp", "localhost:8020")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+		response := new(stubs.StartGameResponse)
+		err = server.Call(stubs.ServerStartGame, stubs.StartGameRequest{
+			ControllerAddress: "localhost:8053",
+			Height:            p.ImageHeight,
+			Width:             p.ImageWidth,
+			MaxTurns:          p.Turns,
+			Threads:           p.Threads,
+			Board:             stubs.BitBoardFromSlice(board, p.ImageHeight, p.ImageWidth),
+			VisualUpdates:     true,
+			OnDisconnect:      stubs.PauseGame,
+			StartNew:          true,
+		}, response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !response.Success {
+			t.Fatal(response.Message)
+		}
+		<-dropping.dropped
+
+		resume := p
+		resume.ImageWidth, resume.ImageHeight = 0, 0
+		resume.ResumeGame = true
+		resume.GameID = response.GameID
+		resume.VisualUpdates = true
+		events := make(chan gol.Event)
+		go gol.Run(resume, events, nil)
+
+		drawn := make([][]bool, p.ImageHeight)
+		for y := range drawn {
+			drawn[y] = make([]bool, p.ImageWidth)
+		}
+		firstTurn := -1
+		var final []util.Cell
+		var started *gol.GameStarted
+		for event := range events {
+			switch e := event.(type) {
+			case gol.GameStarted:
+				started = &e
+			case gol.CellFlipped:
+				drawn[e.Cell.Y][e.Cell.X] = !drawn[e.Cell.Y][e.Cell.X]
+			case gol.TurnComplete:
+				if firstTurn < 0 {
+					firstTurn = e.CompletedTurns
+				}
+			case gol.FinalTurnComplete:
+				final = e.Alive
+			}
+		}
+		if started == nil || started.Width != p.ImageWidth || started.Height != p.ImageHeight {
+			t.Fatalf("Expected the server to give the size of the board as %dx%d, got %+v", p.ImageWidth, p.ImageHeight, started)
+		}
+		if firstTurn < 30 || firstTurn > 31 {
+			t.Errorf("Expected the game to pause at turn 30 or 31, it was taken over at turn %d", firstTurn)
+		}
+		if !assertEqualBoard(t, final, expectedAlive, p) {
+			return
+		}
+		assertEqualBoard(t, util.GetAliveCells(drawn), expectedAlive, p)
+	}
+}
@@ -1,56 +1,99 @@
+//go:build !nosdl
+// +build !nosdl
+
 package sdl
 
 import (
 	"fmt"
+
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/gol"
 )
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
-	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
-
-sdlLoop:
-	for {
-		event := w.PollEvent()
-		if event != nil {
-			switch e := event.(type) {
-			case *sdl.KeyboardEvent:
-				switch e.Keysym.Sym {
-				case sdl.K_p:
-					keyPresses <- 'p'
-				case sdl.K_s:
-					keyPresses <- 's'
-				case sdl.K_q:
-					keyPresses <- 'q'
-				case sdl.K_k:
-					keyPresses <- 'k'
-				case sdl.K_r:
-					keyPresses <- 'r'
-				}
-			}
+// Renderer draws the game in an SDL window
+// SDL must only be used from the thread that created the window, so the renderer is polled for keys
+type Renderer struct {
+	w      *Window
+	keys   chan rune
+	closed bool
+}
+
+func NewRenderer(p gol.Params) *Renderer {
+	return &Renderer{
+		w:    NewWindow(int32(p.ImageWidth), int32(p.ImageHeight)),
+		keys: make(chan rune, 10),
+	}
+}
+
+func (r *Renderer) HandleEvent(event gol.Event) {
+	if r.closed {
+		return
+	}
+	switch e := event.(type) {
+	case gol.CellFlipped:
+		r.w.FlipPixel(e.Cell.X, e.Cell.Y)
+	case gol.TurnComplete:
+		r.w.RenderFrame()
+	case gol.FinalTurnComplete:
+		r.Close()
+	default:
+		if len(event.String()) > 0 {
+			fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+		}
+	}
+}
+
+func (r *Renderer) Keys() <-chan rune {
+	return r.keys
+}
+
+// Poll reads the keys pressed in the window since it was last called
+func (r *Renderer) Poll() {
+	if r.closed {
+		return
+	}
+	for event := r.w.PollEvent(); event != nil; event = r.w.PollEvent() {
+		// Keys are only sent when pressed, not again when they are released
+		e, ok := event.(*sdl.KeyboardEvent)
+		if !ok || e.Type != sdl.KEYDOWN {
+			continue
+		}
+		var key rune
+		switch e.Keysym.Sym {
+		case sdl.K_0, sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4, sdl.K_5, sdl.K_6, sdl.K_7, sdl.K_8, sdl.K_9:
+			// Digits are the count for the next command, e.g. 50n steps 50 turns
+			key = '0' + rune(e.Keysym.Sym-sdl.K_0)
+		case sdl.K_n:
+			key = 'n'
+		case sdl.K_j:
+			key = 'j'
+		case sdl.K_t:
+			key = 't'
+		case sdl.K_p:
+			key = 'p'
+		case sdl.K_s:
+			key = 's'
+		case sdl.K_q:
+			key = 'q'
+		case sdl.K_k:
+			key = 'k'
+		case sdl.K_r:
+			key = 'r'
+		default:
+			continue
 		}
 		select {
-		case event, ok := <-events:
-			if !ok {
-				w.Destroy()
-				break sdlLoop
-			}
-			switch e := event.(type) {
-			case gol.CellFlipped:
-				w.FlipPixel(e.Cell.X, e.Cell.Y)
-			case gol.TurnComplete:
-				w.RenderFrame()
-			case gol.FinalTurnComplete:
-				w.Destroy()
-				break sdlLoop
-			default:
-				if len(event.String()) > 0 {
-					fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
-				}
-			}
+		case r.keys <- key:
 		default:
-			break
 		}
 	}
+}
 
+// Close destroys the window, it can be called more than once
+func (r *Renderer) Close() error {
+	if !r.closed {
+		r.closed = true
+		r.w.Destroy()
+	}
+	return nil
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestStep slows down and pauses a 512x512 game, steps it one turn, then jumps to turn 100 and checks the board shown there.
+func TestStep(t *testing.T) {
+	if util.Status {
+		util.Status = false
+		defer func() { util.Status = true }()
+
+		p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 101, Threads: 8, VisualUpdates: true}
+		expectedAlive := readAliveCells("check/images/512x512x100.pgm", p.ImageWidth, p.ImageHeight)
+
+		events := make(chan gol.Event)
+		keyPresses := make(chan rune, 10)
+		go gol.Run(p, events, keyPresses)
+		// 20 turns a second gives the game time to pause well before turn 100
+		for _, key := range "20tp" {
+			keyPresses <- key
+		}
+
+		drawn := make([][]bool, p.ImageHeight)
+		for y := range drawn {
+			drawn[y] = make([]bool, p.ImageWidth)
+		}
+		pauses := 0
+		pausedTurn := 0
+		var final []util.Cell
+		for event := range events {
+			switch e := event.(type) {
+			case gol.CellFlipped:
+				drawn[e.Cell.Y][e.Cell.X] = !drawn[e.Cell.Y][e.Cell.X]
+			case gol.StateChange:
+				if e.NewState != stubs.Paused {
+					continue
+				}
+				pauses++
+				switch pauses {
+				case 1:
+					if e.CompletedTurns >= 50 {
+						t.Fatalf("Expected the game to be limited to 20 turns a second, it paused at turn %d", e.CompletedTurns)
+					}
+					pausedTurn = e.CompletedTurns
+					keyPresses <- 'n'
+				case 2:
+					if e.CompletedTurns != pausedTurn+1 {
+						t.Errorf("Expected stepping from turn %d to pause at turn %d, it paused at turn %d", pausedTurn, pausedTurn+1, e.CompletedTurns)
+					}
+					// Removing the speed limit doesn't resume the game
+					for _, key := range "t100j" {
+						keyPresses <- key
+					}
+				case 3:
+					if e.CompletedTurns != 100 {
+						t.Errorf("Expected jumping to turn 100 to pause at turn 100, it paused at turn %d", e.CompletedTurns)
+					}
+					assertEqualBoard(t, util.GetAliveCells(drawn), expectedAlive, p)
+					keyPresses <- 'n'
+				}
+			case gol.FinalTurnComplete:
+				final = e.Alive
+			}
+		}
+		if pauses != 3 {
+			t.Errorf("Expected the game to pause 3 times, it paused %d times", pauses)
+		}
+		if final == nil {
+			t.Error("Expected the game to finish after stepping past turn 100")
+		}
+	}
+}
@@ -34,15 +34,16 @@ func GetBitArrayCell(bytes []byte, height, width int, row, col int) bool {
 }
 
 // Decode "decodes" a RLE bit array to an array of bytes
+// The last byte is padded with zero bits when the number of bits isn't a multiple of 8
 func (b *RLEBitArray) Decode() []byte {
-	// Array of bytes to store the bitarray
-	bytes := make([]byte, b.TotalBits/8)
+	// Array of bytes to store the bitarray, rounded up to hold every bit
+	bytes := make([]byte, (b.TotalBits+7)/8)
 	val := false
 	bit := uint(0)
 	// Loop through each run
 	for _, run := range b.Runs {
 		// Set identical bits for the length of the run
-		for r := byte(0); r < run; r++ {
+		for r := byte(0); r < run && bit < b.TotalBits; r++ {
 			// Perform bitwise operations to get the byte and bit indices
 			byteIdx := uint(bit / 8)
 			bitIdx := bit & 7
@@ -0,0 +1,51 @@
+package stubs
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestBitBoardRoundTrip checks boards survive being packed into a bitboard and unpacked again,
+// including sizes which don't fill the last byte
+func TestBitBoardRoundTrip(t *testing.T) {
+	sizes := []struct{ height, width int }{
+		{1, 1}, {3, 3}, {1, 13}, {16, 16}, {7, 9}, {100, 100}, {64, 300},
+	}
+	random := rand.New(rand.NewSource(1))
+	for _, size := range sizes {
+		for _, density := range []float64{0, 0.3, 1} {
+			board := make([][]bool, size.height)
+			for y := range board {
+				board[y] = make([]bool, size.width)
+				for x := range board[y] {
+					board[y][x] = random.Float64() < density
+				}
+			}
+
+			got := BitBoardFromSlice(board, size.height, size.width).ToSlice()
+			if len(got) != size.height {
+				t.Fatalf("%dx%d: got %d rows", size.width, size.height, len(got))
+			}
+			for y := range board {
+				for x := range board[y] {
+					if got[y][x] != board[y][x] {
+						t.Fatalf("%dx%d at density %v: cell (%d, %d) is %v, expected %v",
+							size.width, size.height, density, x, y, got[y][x], board[y][x])
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestDecodePadding checks the last byte of a decoded bit array only has the bits in the array set
+func TestDecodePadding(t *testing.T) {
+	board := [][]bool{{true, true, true}, {true, true, true}, {true, true, true}}
+	bytes := BitBoardFromSlice(board, 3, 3).Bytes.Decode()
+	if len(bytes) != 2 {
+		t.Fatalf("Expected 9 bits to decode to 2 bytes, got %d", len(bytes))
+	}
+	if bytes[0] != 0xff || bytes[1] != 0x01 {
+		t.Errorf("Expected bytes ff 01, got %x", bytes)
+	}
+}
@@ -0,0 +1,35 @@
+package stubs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Files which parallel-incomplete keeps a copy of in its gol package
+var incompleteCopies = []string{"rule.go", "topology.go"}
+
+// TestIncompleteCopies checks the copies in parallel-incomplete haven't drifted from the files here
+func TestIncompleteCopies(t *testing.T) {
+	for _, name := range incompleteCopies {
+		original, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied, err := ioutil.ReadFile(filepath.Join("..", "..", "parallel-incomplete", "gol", name))
+		if os.IsNotExist(err) {
+			t.Skip("parallel-incomplete isn't next to this module")
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		// Everything after the package clause must match
+		original = bytes.SplitN(original, []byte("\n"), 2)[1]
+		copied = bytes.SplitN(copied, []byte("\n"), 2)[1]
+		if !bytes.Equal(original, copied) {
+			t.Errorf("parallel-incomplete/gol/%s differs from stubs/%s", name, name)
+		}
+	}
+}
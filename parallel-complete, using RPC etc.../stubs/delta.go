@@ -0,0 +1,63 @@
+package stubs
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Delta stores the cells which flipped between two boards, so a turn can be sent without the whole board
+// Cells are numbered row by row, and the gaps between the numbers of flipped cells are stored as varints
+// Boards where few cells change take a few bytes, instead of a bit (or run) for every cell
+type Delta struct {
+	RowLength int
+	NumRows   int
+	// Flipped is the number of cells which flipped
+	Flipped int
+	Gaps    []byte
+}
+
+// NewDelta finds the cells which are different between two boards of the same size
+func NewDelta(previous, current [][]bool, height, width int) *Delta {
+	d := &Delta{RowLength: width, NumRows: height}
+	buf := make([]byte, binary.MaxVarintLen64)
+	last := -1
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			if previous[row][col] == current[row][col] {
+				continue
+			}
+			cell := row*width + col
+			n := binary.PutUvarint(buf, uint64(cell-last))
+			d.Gaps = append(d.Gaps, buf[:n]...)
+			d.Flipped++
+			last = cell
+		}
+	}
+	return d
+}
+
+// ForEach calls flip for every cell in the delta, in row order
+func (d *Delta) ForEach(flip func(row, col int)) error {
+	cell := -1
+	data := d.Gaps
+	for i := 0; i < d.Flipped; i++ {
+		gap, n := binary.Uvarint(data)
+		if n <= 0 || gap == 0 {
+			return errors.New("corrupt delta")
+		}
+		data = data[n:]
+		cell += int(gap)
+		if cell >= d.RowLength*d.NumRows {
+			return errors.New("delta cell outside the board")
+		}
+		flip(cell/d.RowLength, cell%d.RowLength)
+	}
+	return nil
+}
+
+// Apply flips the cells in the delta on a board
+func (d *Delta) Apply(board [][]bool) error {
+	return d.ForEach(func(row, col int) {
+		board[row][col] = !board[row][col]
+	})
+}
@@ -0,0 +1,116 @@
+package stubs
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Flip cells of a copy of a board, given as row by row cell numbers
+func flipCells(board [][]bool, cells ...int) [][]bool {
+	width := len(board[0])
+	flipped := make([][]bool, len(board))
+	for row := range board {
+		flipped[row] = make([]bool, width)
+		copy(flipped[row], board[row])
+	}
+	for _, cell := range cells {
+		flipped[cell/width][cell%width] = !flipped[cell/width][cell%width]
+	}
+	return flipped
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	const height, width = 20, 30
+	random := rand.New(rand.NewSource(1))
+	previous := make([][]bool, height)
+	for row := range previous {
+		previous[row] = make([]bool, width)
+		for col := range previous[row] {
+			previous[row][col] = random.Intn(2) == 0
+		}
+	}
+	last := height*width - 1
+	every := make([]int, height*width)
+	for cell := range every {
+		every[cell] = cell
+	}
+
+	tests := []struct {
+		name    string
+		flipped []int
+		// The number of bytes the gaps should take
+		size int
+	}{
+		{"empty", nil, 0},
+		{"first cell", []int{0}, 1},
+		{"last cell", []int{last}, 2},
+		{"first and last cells", []int{0, last}, 3},
+		{"neighbours", []int{40, 41, 42}, 3},
+		// Gaps of 128 or more need a second byte
+		{"gap of 127", []int{126}, 1},
+		{"gap of 128", []int{127}, 2},
+		{"gaps of 128 and more", []int{5, 133, 400, last}, 7},
+		{"every cell", every, height * width},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flipped := test.flipped
+			current := flipCells(previous, flipped...)
+			delta := NewDelta(previous, current, height, width)
+			if delta.Flipped != len(flipped) {
+				t.Errorf("Expected %d flipped cells, got %d", len(flipped), delta.Flipped)
+			}
+			if len(delta.Gaps) != test.size {
+				t.Errorf("Expected the gaps to take %d bytes, got %d", test.size, len(delta.Gaps))
+			}
+
+			var seen []int
+			err := delta.ForEach(func(row, col int) {
+				seen = append(seen, row*width+col)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != len(flipped) {
+				t.Fatalf("Expected cells %v, got %v", flipped, seen)
+			}
+			for i := range seen {
+				if seen[i] != flipped[i] {
+					t.Fatalf("Expected cells %v, got %v", flipped, seen)
+				}
+			}
+
+			board := flipCells(previous)
+			err = delta.Apply(board)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for row := range board {
+				for col := range board[row] {
+					if board[row][col] != current[row][col] {
+						t.Fatalf("Cell (%d, %d) is wrong after applying the delta", col, row)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDeltaCorrupt(t *testing.T) {
+	tests := []struct {
+		name  string
+		delta Delta
+	}{
+		{"missing gaps", Delta{RowLength: 4, NumRows: 4, Flipped: 2, Gaps: []byte{1}}},
+		{"zero gap", Delta{RowLength: 4, NumRows: 4, Flipped: 2, Gaps: []byte{1, 0}}},
+		{"unfinished varint", Delta{RowLength: 4, NumRows: 4, Flipped: 1, Gaps: []byte{0x80}}},
+		{"outside the board", Delta{RowLength: 4, NumRows: 4, Flipped: 1, Gaps: []byte{17}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.delta.ForEach(func(row, col int) {}) == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
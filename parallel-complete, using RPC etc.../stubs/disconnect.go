@@ -0,0 +1,41 @@
+package stubs
+
+import (
+	"errors"
+	"strings"
+)
+
+// DisconnectPolicy chooses what the server does with a game when its controller disconnects without quitting
+// Either way another controller can resume the game and pick up from the turn it has reached
+type DisconnectPolicy int
+
+const (
+	// KeepRunning carries on calculating turns until the game finishes or a controller resumes it
+	// The server pauses the game if it runs without a controller for too long, see its -orphan-timeout flag
+	KeepRunning DisconnectPolicy = iota
+	// PauseGame stops calculating turns until a controller resumes the game
+	PauseGame
+)
+
+// ParseDisconnectPolicy converts a policy name (e.g. "pause") into a DisconnectPolicy
+func ParseDisconnectPolicy(s string) (DisconnectPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "run", "":
+		return KeepRunning, nil
+	case "pause":
+		return PauseGame, nil
+	}
+	return KeepRunning, errors.New("unknown disconnect policy " + s)
+}
+
+// String returns the name of the policy
+func (d DisconnectPolicy) String() string {
+	switch d {
+	case KeepRunning:
+		return "Run"
+	case PauseGame:
+		return "Pause"
+	default:
+		return "Incorrect Disconnect Policy"
+	}
+}
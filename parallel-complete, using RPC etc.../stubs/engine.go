@@ -0,0 +1,53 @@
+package stubs
+
+import (
+	"errors"
+	"strings"
+)
+
+// Engine selects how the server calculates the turns of a game
+type Engine int
+
+const (
+	// Stateless sends every worker its strip of the board and the rows around it each turn
+	Stateless Engine = iota
+	// Stateful leaves each strip on its worker, the workers swap edge rows with each other
+	// and the board is only sent to the server when it is needed
+	Stateful
+	// HashLife runs on the server and skips through huge numbers of turns by remembering
+	// how parts of the board change, it only supports a torus with power of two dimensions
+	HashLife
+	// Sparse runs on the server and only calculates the parts of the board which can change
+	Sparse
+)
+
+// ParseEngine converts an engine name (e.g. "stateful") into an Engine
+func ParseEngine(s string) (Engine, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "stateless", "":
+		return Stateless, nil
+	case "stateful":
+		return Stateful, nil
+	case "hashlife":
+		return HashLife, nil
+	case "sparse":
+		return Sparse, nil
+	}
+	return Stateless, errors.New("unknown engine " + s)
+}
+
+// String returns the name of the engine
+func (e Engine) String() string {
+	switch e {
+	case Stateless:
+		return "Stateless"
+	case Stateful:
+		return "Stateful"
+	case HashLife:
+		return "HashLife"
+	case Sparse:
+		return "Sparse"
+	default:
+		return "Incorrect Engine"
+	}
+}
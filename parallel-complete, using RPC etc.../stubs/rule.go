@@ -0,0 +1,112 @@
+package stubs
+
+import (
+	"errors"
+	"strings"
+)
+
+// This file is shared by stubs in parallel-complete and gol in parallel-incomplete, which can't import one another as both modules are uk.ac.bris.cs/gameoflife
+// The two copies only differ in their package clause, see TestIncompleteCopies in stubs
+
+// Rule describes a Life-like cellular automaton in B/S notation
+// Bit n of Birth is set if a dead cell with n alive neighbours is born
+// Bit n of Survival is set if an alive cell with n alive neighbours survives
+type Rule struct {
+	Birth    uint16
+	Survival uint16
+}
+
+// ConwayRule is the standard Game of Life rule (B3/S23)
+// It is used whenever a game is started without a rule
+var ConwayRule = Rule{Birth: 1 << 3, Survival: 1<<2 | 1<<3}
+
+// namedRules maps well known rule names to their B/S notation
+var namedRules = map[string]string{
+	"life":             "B3/S23",
+	"conway":           "B3/S23",
+	"highlife":         "B36/S23",
+	"daynight":         "B3678/S34678",
+	"seeds":            "B2/S",
+	"lifewithoutdeath": "B3/S012345678",
+	"maze":             "B3/S12345",
+	"replicator":       "B1357/S1357",
+	"2x2":              "B36/S125",
+	"morley":           "B368/S245",
+}
+
+// ParseRule parses a rule written in B/S notation (e.g. "B36/S23") into a Rule
+// The older S/B notation used by RLE files (e.g. "23/3") and the names in namedRules are also accepted
+func ParseRule(s string) (Rule, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if named, ok := namedRules[strings.Replace(s, " ", "", -1)]; ok {
+		s = strings.ToLower(named)
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Rule{}, errors.New("rule " + s + " must have exactly one '/'")
+	}
+
+	rule := Rule{}
+	// S/B notation lists survival counts first with no prefixes
+	if !strings.ContainsAny(s, "bs") {
+		parts[0], parts[1] = "s"+parts[0], "b"+parts[1]
+	}
+	if len(parts[0]) > 0 && len(parts[1]) > 0 && parts[0][0] == parts[1][0] {
+		return Rule{}, errors.New("rule " + s + " must have one B section and one S section")
+	}
+	for _, part := range parts {
+		if len(part) == 0 {
+			return Rule{}, errors.New("rule " + s + " has an empty section")
+		}
+		counts, err := parseNeighbourCounts(part[1:])
+		if err != nil {
+			return Rule{}, err
+		}
+		switch part[0] {
+		case 'b':
+			rule.Birth = counts
+		case 's':
+			rule.Survival = counts
+		default:
+			return Rule{}, errors.New("rule section " + part + " must start with B or S")
+		}
+	}
+	return rule, nil
+}
+
+// parseNeighbourCounts converts a string of digits 0-8 to a bitmask
+func parseNeighbourCounts(digits string) (uint16, error) {
+	counts := uint16(0)
+	for _, d := range digits {
+		if d < '0' || d > '8' {
+			return 0, errors.New("invalid neighbour count " + string(d) + " in rule")
+		}
+		counts |= 1 << uint(d-'0')
+	}
+	return counts, nil
+}
+
+// Next returns the next state of a cell given its current state and number of alive neighbours
+func (r Rule) Next(alive bool, neighbours int) bool {
+	if alive {
+		return r.Survival&(1<<uint(neighbours)) != 0
+	}
+	return r.Birth&(1<<uint(neighbours)) != 0
+}
+
+// String returns the rule in B/S notation
+func (r Rule) String() string {
+	return "B" + neighbourCountsString(r.Birth) + "/S" + neighbourCountsString(r.Survival)
+}
+
+// neighbourCountsString converts a bitmask of neighbour counts back to digits
+func neighbourCountsString(counts uint16) string {
+	digits := ""
+	for n := 0; n <= 8; n++ {
+		if counts&(1<<uint(n)) != 0 {
+			digits += string(rune('0' + n))
+		}
+	}
+	return digits
+}
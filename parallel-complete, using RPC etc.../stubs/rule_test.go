@@ -0,0 +1,83 @@
+package stubs
+
+import "testing"
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b36/s23", "B36/S23"},
+		{" S23/B3 ", "B3/S23"},
+		{"23/3", "B3/S23"},
+		{"23/36", "B36/S23"},
+		{"B3/S012345678", "B3/S012345678"},
+		{"B0/S8", "B0/S8"},
+		// Either set of counts can be empty
+		{"B2/S", "B2/S"},
+		{"B/S3", "B/S3"},
+		{"B/S", "B/S"},
+		{"/2", "B2/S"},
+		{"23/", "B/S23"},
+		{"Life", "B3/S23"},
+		{"HighLife", "B36/S23"},
+		{"Day Night", "B3678/S34678"},
+		{"seeds", "B2/S"},
+	}
+	for _, test := range tests {
+		rule, err := ParseRule(test.rule)
+		if err != nil {
+			t.Errorf("%q: %v", test.rule, err)
+			continue
+		}
+		if rule.String() != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.rule, test.expected, rule)
+		}
+		// The rule's own notation parses back to the same rule
+		if again, err := ParseRule(rule.String()); err != nil || again != rule {
+			t.Errorf("%q: %s parsed back as %s, %v", test.rule, rule, again, err)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"B3S23",
+		"B3/S23/",
+		"B3/S2/S3",
+		"B9/S23",
+		"B3/S2a",
+		"B3/X23",
+		"3/S23",
+		"B3/B23",
+		"S23/S3",
+		"B3 /S23",
+		"unknown",
+	} {
+		if _, err := ParseRule(rule); err == nil {
+			t.Errorf("Expected an error parsing %q", rule)
+		}
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	rule, err := ParseRule("B36/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for neighbours := 0; neighbours <= 8; neighbours++ {
+		born := neighbours == 3 || neighbours == 6
+		survives := neighbours == 2 || neighbours == 3
+		if rule.Next(false, neighbours) != born {
+			t.Errorf("Expected a dead cell with %d neighbours to be born: %v", neighbours, born)
+		}
+		if rule.Next(true, neighbours) != survives {
+			t.Errorf("Expected an alive cell with %d neighbours to survive: %v", neighbours, survives)
+		}
+	}
+	if ConwayRule.String() != "B3/S23" {
+		t.Errorf("Expected ConwayRule to be B3/S23, got %s", ConwayRule)
+	}
+}
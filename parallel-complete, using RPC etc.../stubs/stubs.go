@@ -12,6 +12,7 @@ type Fragment struct {
 // Halo is a subset of a board containing all the cells required to calculate the next turn cells
 // between two parts of the board
 // It stores the board state using a BitBoard, to save space
+// The first and last rows are the rows either side of the fragment, so Offset is always 1
 type Halo struct {
 	BitBoard *BitBoard
 	Offset   int
@@ -50,6 +51,11 @@ var ServerStartGame = "Server.StartGame"
 var ServerRegisterKeypress = "Server.RegisterKeypress"
 var ServerConnectWorker = "Server.ConnectWorker"
 var ServerPing = "Server.Ping"
+var ServerStatus = "Server.Status"
+var ServerObserve = "Server.Observe"
+var ServerDetach = "Server.Detach"
+var ServerStep = "Server.Step"
+var ServerSetSpeed = "Server.SetSpeed"
 
 // Controller RPC strings
 var ControllerGameStateChange = "Controller.GameStateChange"
@@ -61,6 +67,11 @@ var ControllerReportAliveCells = "Controller.ReportAliveCells"
 // Worker RPC strings
 var WorkerDoTurn = "Worker.DoTurn"
 var WorkerShutdown = "Worker.Shutdown"
+var WorkerLoadStrip = "Worker.LoadStrip"
+var WorkerStripTurn = "Worker.StripTurn"
+var WorkerGetEdge = "Worker.GetEdge"
+var WorkerGetStrip = "Worker.GetStrip"
+var WorkerDropStrip = "Worker.DropStrip"
 
 // ServerResponse contains a result from a standard server RPC call
 // Success indicates if the call executed its desired function
@@ -70,26 +81,139 @@ type ServerResponse struct {
 	Message string
 }
 
+// StartGameResponse is returned when a controller starts or resumes a game
+// GameID identifies the game for keypresses and resuming it later
+// Turn is the turn the game has reached, which is more than zero when resuming
+// Height and Width are the size of the game's board, so a controller resuming it doesn't need to know them
+// Rule and Topology are the ones the game uses, which a resumed game keeps whatever the controller asked for
+type StartGameResponse struct {
+	Success  bool
+	Message  string
+	GameID   string
+	Turn     int
+	Height   int
+	Width    int
+	Rule     Rule
+	Topology Topology
+}
+
 // StartGameRequest contains all data required for a controller to connect to a server
 // and start a game
 // This will send the address of the controller, along with information about the board
 // and the starting board state
+// GameID picks the game to resume when StartNew is false, the latest game is used if it is empty
+// TrackAges asks the server to count how long each cell has been alive, so it can be sent with saved boards
+// FrameRate is the most visual updates to send a second, zero sends every turn
+// OnDisconnect is what happens to the game if the controller disconnects without quitting
+// Rule is only used if RuleSet is true, otherwise the game uses ConwayRule
 type StartGameRequest struct {
 	ControllerAddress string
+	GameID            string
 
 	Height        int
 	Width         int
 	MaxTurns      int
 	Threads       int
 	VisualUpdates bool
+	FrameRate     int
+	OnDisconnect  DisconnectPolicy
+	Rule          Rule
+	RuleSet       bool
+	Topology      Topology
+	Engine        Engine
+	TrackAges     bool
 
 	StartNew bool
 	Board    *BitBoard
 }
 
+// ObserveRequest is sent by a controller which wants to watch a game without controlling it
+// GameID picks the game, if it is empty the only running game is used
+// Height and Width must match the game's board if they aren't zero, like when resuming
+// FrameRate is the most boards to send a second for visual updates, the server picks one if it is zero
+type ObserveRequest struct {
+	ObserverAddress string
+	GameID          string
+	Height          int
+	Width           int
+	VisualUpdates   bool
+	FrameRate       int
+}
+
+// ObserveResponse is returned when a controller starts watching a game
+// ObserverID is needed to detach from the game
+// Height, Width, Rule and Topology are those of the game, like in StartGameResponse
+type ObserveResponse struct {
+	Success    bool
+	Message    string
+	GameID     string
+	ObserverID string
+	Turn       int
+	Height     int
+	Width      int
+	Rule       Rule
+	Topology   Topology
+}
+
+// DetachRequest is sent by an observer when it stops watching a game
+type DetachRequest struct {
+	GameID     string
+	ObserverID string
+}
+
+// StatusResponse is returned by the server to show how it is splitting up its games
+type StatusResponse struct {
+	Workers []WorkerStatus
+	Games   []GameStatus
+}
+
+// WorkerStatus contains how fast the server has measured a worker to be
+// RowsPerSecond is zero until the worker has calculated a strip
+type WorkerStatus struct {
+	Address       string
+	RowsPerSecond float64
+}
+
+// GameStatus contains the progress of a game on the server
+// Strips is the split of the board between workers on the last turn calculated
+// Observers is the number of controllers watching the game
+type GameStatus struct {
+	GameID    string
+	Running   bool
+	Engine    Engine
+	Turn      int
+	Height    int
+	Width     int
+	Strips    []StripStatus
+	Observers int
+}
+
+// StripStatus is a strip of the board given to a worker
+// Address is empty if the strip was calculated by the server itself
+type StripStatus struct {
+	Address  string
+	StartRow int
+	EndRow   int
+}
+
 // KeypressRequest is used to send a keypress from a controller to be handled at the server
 type KeypressRequest struct {
-	Key rune
+	GameID string
+	Key    rune
+}
+
+// StepRequest asks the server to run a game for some turns and then pause it, so it can be watched turn by turn
+// Turns is the number of turns to run, or the turn to run to if ToTurn is set
+type StepRequest struct {
+	GameID string
+	Turns  int
+	ToTurn bool
+}
+
+// SpeedRequest sets the most turns a game calculates a second, zero calculates them as fast as possible
+type SpeedRequest struct {
+	GameID         string
+	TurnsPerSecond int
 }
 
 // WorkerConnectRequest is passed by a worker which wishes to connect to the server
@@ -111,24 +235,35 @@ type TurnCompleteReport struct {
 }
 
 // BoardStateReport is passed to the controller to give them the state of the board
+// Ages is only sent when saving a game which tracks ages, see engine.Ages
+// TurnComplete reports send either the whole Board (a keyframe), or the Delta since the last report
+// SkippedTurns is the number of turns since the last TurnComplete report which weren't sent
 type BoardStateReport struct {
 	CompletedTurns int
+	SkippedTurns   int
 
 	Board *BitBoard
+	Ages  [][]int16
+	Delta *Delta
 }
 
 // AliveCellsReport is passed to the controller every 2 seconds to tell them how many
 // cells are alive
+// Engines which split the board into tiles also say how many tiles were calculated on the last turn
 type AliveCellsReport struct {
 	CompletedTurns int
 	NumAlive       int
+	ActiveTiles    int
+	TotalTiles     int
 }
 
 // DoTurnRequest is passed to workers to ask them to calculate the next turn
 // It sends the whole board along with fragment pointers for their portion to calculate
 type DoTurnRequest struct {
-	Halo    Halo
-	Threads int
+	Halo     Halo
+	Threads  int
+	Rule     Rule
+	Topology Topology
 }
 
 // DoTurnResponse is returned by workers to the server containing a fragment of the new board
@@ -139,3 +274,56 @@ type DoTurnResponse struct {
 // Empty is used when there is no information for an RPC function to return
 type Empty struct{}
 
+// EdgeSource tells a worker with a stateful strip where to find the row next to one of its edges
+// If Dead is set the row is off the edge of the board, so all of its cells are dead
+// Otherwise it is the top or bottom edge of the strip held by the worker at Address,
+// reversed if Mirror is set (the top and bottom of a Klein bottle)
+type EdgeSource struct {
+	Dead    bool
+	Address string
+	Bottom  bool
+	Mirror  bool
+}
+
+// LoadStripRequest gives a worker a strip of the board to keep for a stateful game
+// Up and Down say where the rows above and below the strip come from each turn
+type LoadStripRequest struct {
+	GameID   string
+	Turn     int
+	Strip    Fragment
+	Threads  int
+	Rule     Rule
+	Topology Topology
+	Up       EdgeSource
+	Down     EdgeSource
+}
+
+// StripTurnRequest asks a worker to calculate the next turn of its strip
+// Turn is the turn the strip is currently on, so a repeated request isn't calculated twice
+type StripTurnRequest struct {
+	GameID string
+	Turn   int
+}
+
+// EdgeRequest is passed between workers to get the top or bottom row of a strip on a turn
+type EdgeRequest struct {
+	GameID string
+	Turn   int
+	Bottom bool
+}
+
+// EdgeResponse contains the row asked for by an EdgeRequest
+type EdgeResponse struct {
+	Row *BitBoard
+}
+
+// StripRequest identifies the strip of a game held by a worker
+type StripRequest struct {
+	GameID string
+}
+
+// StripResponse is returned by workers with the current state of their strip
+type StripResponse struct {
+	Turn  int
+	Strip Fragment
+}
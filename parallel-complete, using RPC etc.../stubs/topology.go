@@ -0,0 +1,87 @@
+package stubs
+
+import (
+	"errors"
+	"strings"
+)
+
+// This file is shared by stubs in parallel-complete and gol in parallel-incomplete, which can't import one another as both modules are uk.ac.bris.cs/gameoflife
+// The two copies only differ in their package clause, see TestIncompleteCopies in stubs
+
+// Topology describes how the edges of the board are joined together
+type Topology int
+
+const (
+	// Torus joins the left edge to the right edge and the top edge to the bottom edge
+	Torus Topology = iota
+	// Plane doesn't join any edges, every cell off the board is treated as dead
+	Plane
+	// Cylinder joins the left edge to the right edge, cells above and below the board are dead
+	Cylinder
+	// KleinBottle joins the left edge to the right edge, and the top edge to the bottom edge
+	// with the columns reversed
+	KleinBottle
+)
+
+// ParseTopology converts a topology name (e.g. "torus") into a Topology
+func ParseTopology(s string) (Topology, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "torus", "":
+		return Torus, nil
+	case "plane", "finite", "dead":
+		return Plane, nil
+	case "cylinder":
+		return Cylinder, nil
+	case "klein", "kleinbottle", "klein-bottle":
+		return KleinBottle, nil
+	}
+	return Torus, errors.New("unknown topology " + s)
+}
+
+// String returns the name of the topology
+func (t Topology) String() string {
+	switch t {
+	case Torus:
+		return "Torus"
+	case Plane:
+		return "Plane"
+	case Cylinder:
+		return "Cylinder"
+	case KleinBottle:
+		return "KleinBottle"
+	default:
+		return "Incorrect Topology"
+	}
+}
+
+// WrapsColumns reports whether the left and right edges of the board are joined
+func (t Topology) WrapsColumns() bool {
+	return t != Plane
+}
+
+// WrapsRows reports whether the top and bottom edges of the board are joined
+func (t Topology) WrapsRows() bool {
+	return t == Torus || t == KleinBottle
+}
+
+// Neighbour maps a cell position which may be just off the board back onto the board
+// Returns false if the position falls off an edge which isn't joined, so the cell is dead
+func (t Topology) Neighbour(row, col, height, width int) (int, int, bool) {
+	if row < 0 || row >= height {
+		if !t.WrapsRows() {
+			return 0, 0, false
+		}
+		row = (row + height) % height
+		// Crossing the top or bottom of a Klein bottle reverses the columns
+		if t == KleinBottle {
+			col = width - 1 - col
+		}
+	}
+	if col < 0 || col >= width {
+		if !t.WrapsColumns() {
+			return 0, 0, false
+		}
+		col = (col + width) % width
+	}
+	return row, col, true
+}
@@ -0,0 +1,94 @@
+package stubs
+
+import "testing"
+
+func TestParseTopology(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Topology
+	}{
+		{"", Torus},
+		{"torus", Torus},
+		{"Plane", Plane},
+		{"finite", Plane},
+		{"dead", Plane},
+		{" cylinder ", Cylinder},
+		{"klein", KleinBottle},
+		{"KleinBottle", KleinBottle},
+		{"klein-bottle", KleinBottle},
+	}
+	for _, test := range tests {
+		topology, err := ParseTopology(test.name)
+		if err != nil {
+			t.Errorf("%q: %v", test.name, err)
+		} else if topology != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.name, test.expected, topology)
+		}
+	}
+	for _, topology := range []Topology{Torus, Plane, Cylinder, KleinBottle} {
+		if parsed, err := ParseTopology(topology.String()); err != nil || parsed != topology {
+			t.Errorf("Expected %v to parse back to itself, got %v, %v", topology, parsed, err)
+		}
+	}
+	for _, name := range []string{"sphere", "torus2", "klein bottle"} {
+		if _, err := ParseTopology(name); err == nil {
+			t.Errorf("Expected an error parsing %q", name)
+		}
+	}
+}
+
+// TestNeighbour checks the cells just off each edge and corner of a 4x5 board
+func TestNeighbour(t *testing.T) {
+	const height, width = 4, 5
+	// off is a position which falls off the board
+	off := [2]int{-1, -1}
+	tests := []struct {
+		name                          string
+		row, col                      int
+		torus, plane, cylinder, klein [2]int
+	}{
+		{"inside", 1, 2, [2]int{1, 2}, [2]int{1, 2}, [2]int{1, 2}, [2]int{1, 2}},
+		{"top", -1, 1, [2]int{3, 1}, off, off, [2]int{3, 3}},
+		{"bottom", 4, 1, [2]int{0, 1}, off, off, [2]int{0, 3}},
+		{"left", 2, -1, [2]int{2, 4}, off, [2]int{2, 4}, [2]int{2, 4}},
+		{"right", 2, 5, [2]int{2, 0}, off, [2]int{2, 0}, [2]int{2, 0}},
+		{"top left", -1, -1, [2]int{3, 4}, off, off, [2]int{3, 0}},
+		{"top right", -1, 5, [2]int{3, 0}, off, off, [2]int{3, 4}},
+		{"bottom left", 4, -1, [2]int{0, 4}, off, off, [2]int{0, 0}},
+		{"bottom right", 4, 5, [2]int{0, 0}, off, off, [2]int{0, 4}},
+	}
+	for _, test := range tests {
+		for topology, expected := range map[Topology][2]int{
+			Torus:       test.torus,
+			Plane:       test.plane,
+			Cylinder:    test.cylinder,
+			KleinBottle: test.klein,
+		} {
+			row, col, ok := topology.Neighbour(test.row, test.col, height, width)
+			got := [2]int{row, col}
+			if !ok {
+				got = off
+			}
+			if got != expected {
+				t.Errorf("%v %s (%d, %d): expected %v, got %v", topology, test.name, test.row, test.col, expected, got)
+			}
+		}
+	}
+}
+
+func TestWraps(t *testing.T) {
+	tests := []struct {
+		topology      Topology
+		rows, columns bool
+	}{
+		{Torus, true, true},
+		{Plane, false, false},
+		{Cylinder, false, true},
+		{KleinBottle, true, true},
+	}
+	for _, test := range tests {
+		if test.topology.WrapsRows() != test.rows || test.topology.WrapsColumns() != test.columns {
+			t.Errorf("%v: expected rows %v and columns %v to wrap", test.topology, test.rows, test.columns)
+		}
+	}
+}
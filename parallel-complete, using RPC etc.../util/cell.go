@@ -1,9 +1,9 @@
 package util
 
 import (
-	"io/ioutil"
+	"errors"
+	"os"
 	"strconv"
-	"strings"
 )
 
 // Cell is used as the return type for the testing framework.
@@ -26,46 +26,38 @@ func GetAliveCells(board [][]bool) []Cell {
 	return aliveCells
 }
 
-func ReadAliveCells(path string, width, height int) []Cell {
-	//data, ioError := ioutil.ReadFile("check/images/" + fmt.Sprintf("%vx%vx%v.pgm", width, height, turns))
-	data, ioError := ioutil.ReadFile(path)
-	Check(ioError)
-
-	fields := strings.Fields(string(data))
-
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
+// ReadAliveCells reads the alive cells from a PNM image, checking it is the expected size
+func ReadAliveCells(path string, width, height int) ([]Cell, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	imageWidth, _ := strconv.Atoi(fields[1])
-	if imageWidth != width {
-		panic("Incorrect width")
+	image, err := NewPNMReader(file)
+	if err != nil {
+		return nil, err
 	}
-
-	imageHeight, _ := strconv.Atoi(fields[2])
-	if imageHeight != height {
-		panic("Incorrect height")
+	if image.Width != width || image.Height != height {
+		return nil, errors.New(path + " is " + strconv.Itoa(image.Width) + "x" + strconv.Itoa(image.Height) +
+			", expected " + strconv.Itoa(width) + "x" + strconv.Itoa(height))
 	}
 
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
-	}
-
-	image := []byte(fields[4])
-
 	var cells []Cell
+	row := make([]byte, width)
 	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			cell := image[0]
+		err = image.ReadRow(row)
+		if err != nil {
+			return nil, err
+		}
+		for x, cell := range row {
 			if cell != 0 {
 				cells = append(cells, Cell{
 					X: x,
 					Y: y,
 				})
 			}
-			image = image[1:]
 		}
 	}
-	return cells
+	return cells, nil
 }
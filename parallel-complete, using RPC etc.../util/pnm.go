@@ -0,0 +1,346 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// This file contains a streaming reader and writer for the netpbm image formats
+// P1 and P4 are bitmaps (ASCII and binary), P2 and P5 are greymaps (ASCII and binary)
+// See http://netpbm.sourceforge.net/doc/ for the format descriptions
+
+// PNMReader reads the pixels of a PNM image one row at a time
+type PNMReader struct {
+	// Format is the magic number of the image, e.g. "P5"
+	Format string
+	Width  int
+	Height int
+	// MaxVal is the value of a white pixel, it is 1 for bitmaps
+	MaxVal int
+
+	// Threshold is the fraction of MaxVal a greymap pixel must reach to be alive
+	// Zero means every pixel that isn't black is alive
+	// Bitmap pixels are alive when they are set (black)
+	Threshold float64
+
+	r        *bufio.Reader
+	rowsRead int
+	raw      []byte
+}
+
+// NewPNMReader reads the header of a PNM image, ready for the rows to be read
+func NewPNMReader(r io.Reader) (*PNMReader, error) {
+	p := &PNMReader{r: bufio.NewReader(r)}
+
+	magic := make([]byte, 2)
+	_, err := io.ReadFull(p.r, magic)
+	if err != nil {
+		return nil, errors.New("not a pnm file: " + err.Error())
+	}
+	p.Format = string(magic)
+	switch p.Format {
+	case "P1", "P2", "P4", "P5":
+	default:
+		return nil, errors.New("unsupported pnm format " + strconv.Quote(p.Format))
+	}
+
+	p.Width, err = p.readNumber()
+	if err != nil {
+		return nil, err
+	}
+	p.Height, err = p.readNumber()
+	if err != nil {
+		return nil, err
+	}
+	if p.Width <= 0 || p.Height <= 0 {
+		return nil, errors.New("pnm image has no pixels")
+	}
+
+	p.MaxVal = 1
+	if p.Format == "P2" || p.Format == "P5" {
+		p.MaxVal, err = p.readNumber()
+		if err != nil {
+			return nil, err
+		}
+		if p.MaxVal <= 0 || p.MaxVal > 65535 {
+			return nil, errors.New("invalid pnm maxval " + strconv.Itoa(p.MaxVal))
+		}
+	}
+
+	// Binary formats have exactly one whitespace character between the header and the pixels
+	if p.Format == "P4" || p.Format == "P5" {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if !isPNMSpace(c) {
+			return nil, errors.New("missing whitespace after pnm header")
+		}
+	}
+	return p, nil
+}
+
+// ReadRow reads the next row of the image into row, which must be Width long
+// Alive pixels are set to 255 and dead pixels to 0
+func (p *PNMReader) ReadRow(row []byte) error {
+	if len(row) != p.Width {
+		return errors.New("row must be " + strconv.Itoa(p.Width) + " pixels long")
+	}
+	if p.rowsRead == p.Height {
+		return io.EOF
+	}
+	p.rowsRead++
+
+	switch p.Format {
+	case "P1":
+		for x := range row {
+			c, err := p.skipSpace()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if c != '0' && c != '1' {
+				return errors.New("invalid pbm pixel " + strconv.QuoteRune(rune(c)))
+			}
+			row[x] = aliveByte(c == '1')
+		}
+	case "P2":
+		for x := range row {
+			value, err := p.readNumber()
+			if err != nil {
+				return err
+			}
+			row[x] = aliveByte(p.alive(value))
+		}
+	case "P4":
+		raw := p.rawRow((p.Width + 7) / 8)
+		_, err := io.ReadFull(p.r, raw)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		for x := range row {
+			row[x] = aliveByte(raw[x/8]&(0x80>>uint(x%8)) != 0)
+		}
+	case "P5":
+		// Values over 255 take two bytes, most significant first
+		bytesPerPixel := 1
+		if p.MaxVal > 255 {
+			bytesPerPixel = 2
+		}
+		raw := p.rawRow(p.Width * bytesPerPixel)
+		_, err := io.ReadFull(p.r, raw)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		for x := range row {
+			value := int(raw[x])
+			if bytesPerPixel == 2 {
+				value = int(raw[2*x])<<8 | int(raw[2*x+1])
+			}
+			row[x] = aliveByte(p.alive(value))
+		}
+	}
+	return nil
+}
+
+// ReadAll reads every remaining row of the image
+func (p *PNMReader) ReadAll() ([][]byte, error) {
+	image := make([][]byte, 0, p.Height-p.rowsRead)
+	for p.rowsRead < p.Height {
+		row := make([]byte, p.Width)
+		err := p.ReadRow(row)
+		if err != nil {
+			return nil, err
+		}
+		image = append(image, row)
+	}
+	return image, nil
+}
+
+// Work out if a greymap value is alive
+func (p *PNMReader) alive(value int) bool {
+	if p.Threshold <= 0 {
+		return value != 0
+	}
+	return float64(value) >= p.Threshold*float64(p.MaxVal)
+}
+
+// Get a buffer for a row of raw bytes, reusing the last one if it is big enough
+func (p *PNMReader) rawRow(length int) []byte {
+	if cap(p.raw) < length {
+		p.raw = make([]byte, length)
+	}
+	return p.raw[:length]
+}
+
+// Skip whitespace and comments, returning the next character
+func (p *PNMReader) skipSpace() (byte, error) {
+	for {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c == '#' {
+			_, err = p.r.ReadString('\n')
+			if err != nil {
+				return 0, err
+			}
+		} else if !isPNMSpace(c) {
+			return c, nil
+		}
+	}
+}
+
+// Read a decimal number from the header or an ASCII image
+func (p *PNMReader) readNumber() (int, error) {
+	c, err := p.skipSpace()
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if c < '0' || c > '9' {
+		return 0, errors.New("expected a number in pnm file, found " + strconv.QuoteRune(rune(c)))
+	}
+
+	n := 0
+	for {
+		n = n*10 + int(c-'0')
+		if n > 1<<30 {
+			return 0, errors.New("number in pnm file is too large")
+		}
+		c, err = p.r.ReadByte()
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if c < '0' || c > '9' {
+			// Comments can follow a number straight away, so let skipSpace deal with them
+			return n, p.r.UnreadByte()
+		}
+	}
+}
+
+// PNMWriter writes a PNM image one row at a time
+// Greymaps are written with a maxval of 255, with alive pixels white
+// Bitmaps are written with alive pixels set (black)
+type PNMWriter struct {
+	Format string
+	Width  int
+	Height int
+
+	w           *bufio.Writer
+	rowsWritten int
+	raw         []byte
+}
+
+// NewPNMWriter writes the header of a PNM image, ready for the rows to be written
+func NewPNMWriter(w io.Writer, format string, width, height int) (*PNMWriter, error) {
+	p := &PNMWriter{Format: format, Width: width, Height: height, w: bufio.NewWriter(w)}
+	header := format + "\n" + strconv.Itoa(width) + " " + strconv.Itoa(height) + "\n"
+	switch format {
+	case "P1", "P4":
+	case "P2", "P5":
+		header += "255\n"
+	default:
+		return nil, errors.New("unsupported pnm format " + strconv.Quote(format))
+	}
+	_, err := p.w.WriteString(header)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// WriteRow writes the next row of the image, where any non-zero pixel is alive
+func (p *PNMWriter) WriteRow(row []byte) error {
+	if len(row) != p.Width {
+		return errors.New("row must be " + strconv.Itoa(p.Width) + " pixels long")
+	}
+	if p.rowsWritten == p.Height {
+		return errors.New("too many rows written to pnm image")
+	}
+	p.rowsWritten++
+
+	switch p.Format {
+	case "P1", "P2":
+		// ASCII lines should be no longer than 70 characters
+		dead, alive := "0", "1"
+		if p.Format == "P2" {
+			alive = "255"
+		}
+		line := 0
+		for x, b := range row {
+			pixel := dead
+			if b != 0 {
+				pixel = alive
+			}
+			if x > 0 && line+len(pixel)+1 > 70 {
+				p.w.WriteByte('\n')
+				line = 0
+			} else if x > 0 {
+				p.w.WriteByte(' ')
+				line++
+			}
+			p.w.WriteString(pixel)
+			line += len(pixel)
+		}
+		return p.w.WriteByte('\n')
+	case "P4":
+		raw := p.rawRow((p.Width + 7) / 8)
+		for i := range raw {
+			raw[i] = 0
+		}
+		for x, b := range row {
+			if b != 0 {
+				raw[x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+		_, err := p.w.Write(raw)
+		return err
+	default:
+		raw := p.rawRow(p.Width)
+		for x, b := range row {
+			raw[x] = aliveByte(b != 0)
+		}
+		_, err := p.w.Write(raw)
+		return err
+	}
+}
+
+// Flush writes any buffered data, it must be called once every row has been written
+func (p *PNMWriter) Flush() error {
+	if p.rowsWritten != p.Height {
+		return errors.New("only " + strconv.Itoa(p.rowsWritten) + " of " + strconv.Itoa(p.Height) + " rows written to pnm image")
+	}
+	return p.w.Flush()
+}
+
+// Get a buffer for a row of raw bytes, reusing the last one if it is big enough
+func (p *PNMWriter) rawRow(length int) []byte {
+	if cap(p.raw) < length {
+		p.raw = make([]byte, length)
+	}
+	return p.raw[:length]
+}
+
+// Whitespace as defined by the PNM formats
+func isPNMSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// Convert an alive state to a pixel byte
+func aliveByte(alive bool) byte {
+	if alive {
+		return 255
+	}
+	return 0
+}
+
+// Running out of data in the middle of an image is always an error
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
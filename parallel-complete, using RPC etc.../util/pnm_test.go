@@ -0,0 +1,191 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Make the expected image from rows of '.' for dead pixels and '#' for alive ones
+func pixelRows(rows ...string) [][]byte {
+	image := make([][]byte, len(rows))
+	for y, row := range rows {
+		image[y] = make([]byte, len(row))
+		for x, c := range row {
+			image[y][x] = aliveByte(c == '#')
+		}
+	}
+	return image
+}
+
+func TestPNMReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		threshold float64
+		expected  [][]byte
+	}{
+		{"P1", "P1\n3 2\n0 1 0\n1 1 1\n", 0, pixelRows(".#.", "###")},
+		{"P1 comments", "P1\n# made by hand\n3 # width\n2\n# pixels\n0 1 0\n1 1 1\n", 0, pixelRows(".#.", "###")},
+		{"P1 without spaces", "P1 3 2 010111", 0, pixelRows(".#.", "###")},
+		{"P2", "P2\n3 2\n255\n0 255 0\n1 0 255\n", 0, pixelRows(".#.", "#.#")},
+		{"P2 comment after maxval", "P2 2 1 255# comment\n0 255\n", 0, pixelRows(".#")},
+		{"P2 maxval 15", "P2\n3 1\n15\n0 7 15\n", 0, pixelRows(".##")},
+		{"P2 maxval 15 threshold", "P2\n3 1\n15\n0 7 15\n", 0.5, pixelRows("..#")},
+		{"P4", "P4\n10 2\n" + string([]byte{0x80, 0x40, 0xff, 0xc0}), 0, pixelRows("#........#", "##########")},
+		{"P4 comment", "P4\n# bits\n3 1\n" + string([]byte{0xa0}), 0, pixelRows("#.#")},
+		{"P5", "P5\n4 1\n255\n" + string([]byte{0, 1, 128, 255}), 0, pixelRows(".###")},
+		{"P5 threshold", "P5\n4 1\n255\n" + string([]byte{0, 127, 128, 255}), 0.5, pixelRows("..##")},
+		{"P5 maxval 100", "P5\n3 1\n100\n" + string([]byte{0, 49, 50}), 0.5, pixelRows("..#")},
+		{"P5 two bytes", "P5\n3 1\n1000\n" + string([]byte{0x00, 0x00, 0x01, 0xf3, 0x03, 0xe8}), 0.5, pixelRows("..#")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewPNMReader(strings.NewReader(test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader.Threshold = test.threshold
+			image, err := reader.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reader.Width != len(test.expected[0]) || reader.Height != len(test.expected) {
+				t.Fatalf("Expected a %dx%d image, got %dx%d", len(test.expected[0]), len(test.expected), reader.Width, reader.Height)
+			}
+			for y := range test.expected {
+				if !bytes.Equal(image[y], test.expected[y]) {
+					t.Errorf("Row %d: expected %v, got %v", y, test.expected[y], image[y])
+				}
+			}
+		})
+	}
+}
+
+func TestPNMReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"unsupported format", "P3\n1 1\n255\n0 0 0\n"},
+		{"empty file", ""},
+		{"no pixels", "P1\n0 2\n"},
+		{"missing height", "P1\n3"},
+		{"maxval too big", "P2\n1 1\n70000\n0\n"},
+		{"bad pixel", "P1\n1 1\n2\n"},
+		{"short P4", "P4\n16 2\n" + string([]byte{0xff, 0xff, 0xff})},
+		{"short P5", "P5\n2 2\n255\n" + string([]byte{0, 0, 0})},
+		{"short P2", "P2\n2 2\n255\n0 0 0\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewPNMReader(strings.NewReader(test.file))
+			if err == nil {
+				_, err = reader.ReadAll()
+			}
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestPNMWriter(t *testing.T) {
+	image := pixelRows("#..", ".##")
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"P1", "P1\n3 2\n1 0 0\n0 1 1\n"},
+		{"P2", "P2\n3 2\n255\n255 0 0\n0 255 255\n"},
+		{"P4", "P4\n3 2\n" + string([]byte{0x80, 0x60})},
+		{"P5", "P5\n3 2\n255\n" + string([]byte{255, 0, 0, 0, 255, 255})},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var file bytes.Buffer
+			writer, err := NewPNMWriter(&file, test.format, 3, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range image {
+				err = writer.WriteRow(row)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = writer.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.String() != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, file.String())
+			}
+		})
+	}
+}
+
+// TestPNMRoundTrip writes images in every format and reads them back, with ASCII rows long enough to be wrapped
+func TestPNMRoundTrip(t *testing.T) {
+	image := pixelRows(strings.Repeat("#.##.", 20), strings.Repeat("#", 100), strings.Repeat(".", 100))
+	for _, format := range []string{"P1", "P2", "P4", "P5"} {
+		t.Run(format, func(t *testing.T) {
+			var file bytes.Buffer
+			writer, err := NewPNMWriter(&file, format, 100, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range image {
+				err = writer.WriteRow(row)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = writer.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format == "P1" || format == "P2" {
+				for _, line := range strings.Split(file.String(), "\n") {
+					if len(line) > 70 {
+						t.Errorf("Expected lines of at most 70 characters, got %d", len(line))
+					}
+				}
+			}
+
+			reader, err := NewPNMReader(&file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			read, err := reader.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for y := range image {
+				if !bytes.Equal(read[y], image[y]) {
+					t.Errorf("Row %d doesn't match", y)
+				}
+			}
+		})
+	}
+}
+
+func TestPNMWriterRows(t *testing.T) {
+	var file bytes.Buffer
+	writer, err := NewPNMWriter(&file, "P5", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.WriteRow([]byte{0}) == nil {
+		t.Error("Expected an error writing a row of the wrong length")
+	}
+	if writer.Flush() == nil {
+		t.Error("Expected an error flushing before every row is written")
+	}
+	if writer.WriteRow([]byte{0, 255}) != nil || writer.WriteRow([]byte{0, 255}) == nil {
+		t.Error("Expected an error writing too many rows")
+	}
+	if _, err := NewPNMWriter(&file, "P6", 1, 1); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
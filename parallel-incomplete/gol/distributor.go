@@ -33,44 +33,43 @@ func distributor(p Params, c distributorChannels) {
 
 	// TODO: Execute all turns of the Game of Life.
 
-	if p.Threads == 1 {
-		for i := 0; i < p.Turns; i++ {
-			world = calculateNextState(world, 0, p.ImageWidth)
-			turn += 1
-		}
-	} else {
-		workerHeight := p.ImageHeight / p.Threads
-		r := p.ImageHeight % p.Threads
-		out := make([]chan [][]uint8, p.Threads)
-		worldB := newWorld(0, 0)
-		for i := range out {
-			out[i] = make(chan [][]uint8)
-		}
+	// The world is packed into 64 bit words for the turns, so 64 cells are calculated at once
+	packed := packWorld(world, p.ImageHeight, p.ImageWidth)
+	next := newPackedWorld(p.ImageHeight, p.ImageWidth)
 
-		for i := 0; i < p.Turns; i++ {
-			for j := 0; j < p.Threads; j++ {
-				if (p.Threads - j) <= r {
-					go worker(world, j*workerHeight, (1+j)*workerHeight+1, out[j]) /// fix!!!!
-				} else {
-					go worker(world, j*workerHeight, (1+j)*workerHeight, out[j])
-				}
-			}
-			for t := 0; t < p.Threads; t++ {
-				part := <-out[t]
-				worldB = append(worldB, part...)
+	threads := p.Threads
+	if threads > p.ImageHeight {
+		threads = p.ImageHeight
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	workerHeight := p.ImageHeight / threads
+	done := make(chan bool, threads)
+
+	for i := 0; i < p.Turns; i++ {
+		for j := 0; j < threads; j++ {
+			// The last worker also takes any rows left over
+			endY := (j + 1) * workerHeight
+			if j == threads-1 {
+				endY = p.ImageHeight
 			}
-			fmt.Println("Len:", len(worldB))
-			world = worldB
-			turn += 1
+			go worker(packed, next, j*workerHeight, endY, p.ImageWidth, p.Rule, p.Topology, done)
 		}
+		for t := 0; t < threads; t++ {
+			<-done
+		}
+		packed, next = next, packed
+		turn += 1
 	}
+	world = packed.unpack(p.ImageHeight, p.ImageWidth)
 
 	// TODO: Report the final state using FinalTurnCompleteEvent.
 	alive := make([]util.Cell, 0)
 	for y := 0; y < p.ImageHeight; y++ {
 		for x := 0; x < p.ImageWidth; x++ {
 			if int(world[y][x]) > 0 {
-				cell := util.Cell{x, y}
+				cell := util.Cell{X: x, Y: y}
 				alive = append(alive, cell)
 			}
 		}
@@ -88,38 +87,17 @@ func distributor(p Params, c distributorChannels) {
 	close(c.events)
 }
 
-func calculateNextState(world [][]uint8, startY, endY int) [][]uint8 {
-	sumAlive := 0
+// Calculate the next state of rows startY to endY, writing them to the same rows of next
+func calculateNextState(world, next packedWorld, startY, endY, imageWidth int, rule Rule, topology Topology) {
 	IH := len(world)
-	IW := len(world[0])
-	worldB := newWorld(IH, IW)
 	for y := startY; y < endY; y++ {
-		for x := 0; x < IW; x++ {
-			sumAlive = 0
-			sumAlive = int(world[(y+IH+1)%IH][(x+IW-1)%IW]) + int(world[(y+IH+1)%IH][(x+IW)%IW]) +
-				int(world[(y+IH+1)%IH][(x+IW+1)%IW]) + int(world[(y+IH)%IH][(x+IW-1)%IW]) +
-				int(world[(y+IH)%IH][(x+IW+1)%IW]) + int(world[(y+IH-1)%IH][(x+IW-1)%IW]) +
-				int(world[(y+IH-1)%IH][(x+IW)%IW]) + int(world[(y+IH-1)%IH][(x+IW+1)%IW])
-			if int(world[y][x]) > 0 {
-				if sumAlive < 510 {
-					worldB[y][x] = 0
-				} else if sumAlive == 510 || sumAlive == 765 {
-					worldB[y][x] = 255
-				} else {
-					worldB[y][x] = 0
-				}
-			} else {
-				if sumAlive == 765 {
-					worldB[y][x] = 255
-				} else {
-					worldB[y][x] = 0
-				}
-			}
-		}
+		// the topology decides which rows are above and below the edges, or if they are dead
+		up := world.edgeRow(y-1, IH, imageWidth, topology)
+		down := world.edgeRow(y+1, IH, imageWidth, topology)
+		stepRow(up, world[y], down, next[y], imageWidth, rule, topology.WrapsColumns())
 	}
-	worldB = worldB[startY:endY]
-	return worldB
 }
+
 func newWorld(imageHeight, imageWidth int) [][]uint8 {
 	world := make([][]uint8, imageHeight)
 	for i := 0; i < imageHeight; i++ {
@@ -128,7 +106,7 @@ func newWorld(imageHeight, imageWidth int) [][]uint8 {
 	return world
 }
 
-func worker(world [][]uint8, startY, endY int, out chan<- [][]uint8) {
-	part := calculateNextState(world, startY, endY)
-	out <- part
+func worker(world, next packedWorld, startY, endY, imageWidth int, rule Rule, topology Topology, done chan<- bool) {
+	calculateNextState(world, next, startY, endY, imageWidth, rule, topology)
+	done <- true
 }
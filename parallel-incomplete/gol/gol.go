@@ -1,15 +1,22 @@
 package gol
 
 // Params provides the details of how to run the Game of Life and which image to load.
+// Rule is only used if RuleSet is true, otherwise the game uses ConwayRule
 type Params struct {
 	Turns       int
 	Threads     int
 	ImageWidth  int
 	ImageHeight int
+	Rule        Rule
+	RuleSet     bool
+	Topology    Topology
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+	if !p.RuleSet {
+		p.Rule = ConwayRule
+	}
 
 	//	TODO: Put the missing channels in here.
 
@@ -0,0 +1,147 @@
+package gol
+
+// This file contains a Game of Life kernel which works on 64 cells at once
+// Each row is packed into 64 bit words, and the neighbours of every cell in a word
+// are added up together using bitwise adders, so cells are never looked at one by one
+
+// Number of cells packed into each word
+const wordSize = 64
+
+// packedWorld stores the world with each row packed into 64 bit words
+// Cell (y, x) is bit x%64 of word x/64 in row y, any bits past the width are always 0
+type packedWorld [][]uint64
+
+// Create a packed world with every cell dead
+func newPackedWorld(imageHeight, imageWidth int) packedWorld {
+	words := (imageWidth + wordSize - 1) / wordSize
+	world := make(packedWorld, imageHeight)
+	for i := range world {
+		world[i] = make([]uint64, words)
+	}
+	return world
+}
+
+// Pack a world of bytes, where any value above 0 is alive
+func packWorld(world [][]uint8, imageHeight, imageWidth int) packedWorld {
+	packed := newPackedWorld(imageHeight, imageWidth)
+	for y := 0; y < imageHeight; y++ {
+		for x := 0; x < imageWidth; x++ {
+			if world[y][x] > 0 {
+				packed[y][x/wordSize] |= 1 << uint(x%wordSize)
+			}
+		}
+	}
+	return packed
+}
+
+// Unpack a packed world back to bytes, with alive cells set to 255
+func (p packedWorld) unpack(imageHeight, imageWidth int) [][]uint8 {
+	world := newWorld(imageHeight, imageWidth)
+	for y := 0; y < imageHeight; y++ {
+		for x := 0; x < imageWidth; x++ {
+			if p[y][x/wordSize]&(1<<uint(x%wordSize)) != 0 {
+				world[y][x] = 255
+			}
+		}
+	}
+	return world
+}
+
+// Get the row next to the top or bottom edge of the world, which depends on the topology
+// Rows off an edge which isn't joined are dead, and a Klein bottle reverses the row when crossing
+func (p packedWorld) edgeRow(y, imageHeight, imageWidth int, topology Topology) []uint64 {
+	if y >= 0 && y < imageHeight {
+		return p[y]
+	}
+	edge := make([]uint64, len(p[0]))
+	for x := 0; x < imageWidth; x++ {
+		if row, col, ok := topology.Neighbour(y, x, imageHeight, imageWidth); ok && p[row][col/wordSize]&(1<<uint(col%wordSize)) != 0 {
+			edge[x/wordSize] |= 1 << uint(x%wordSize)
+		}
+	}
+	return edge
+}
+
+// Calculate the next state of a packed row from the rows above and below it
+// The new row is written to out, which must be the same length as row
+// If wrap is set the left and right edges of the world are joined
+func stepRow(up, row, down, out []uint64, imageWidth int, rule Rule, wrap bool) {
+	last := len(row) - 1
+	lastBit := uint((imageWidth - 1) % wordSize)
+
+	// Work out which neighbour counts give an alive cell before going through the words
+	var births, survivals [9]bool
+	for n := 0; n <= 8; n++ {
+		births[n] = rule.Next(false, n)
+		survivals[n] = rule.Next(true, n)
+	}
+
+	for w := 0; w <= last; w++ {
+		upWest, upEast := shiftRow(up, w, last, lastBit, wrap)
+		west, east := shiftRow(row, w, last, lastBit, wrap)
+		downWest, downEast := shiftRow(down, w, last, lastBit, wrap)
+
+		// Add up the 8 neighbours of every cell, giving a 4 bit count spread over count0-count3
+		sum0, carry0 := fullAdd(upWest, up[w], upEast)
+		sum1, carry1 := fullAdd(west, east, down[w])
+		sum2, carry2 := downWest^downEast, downWest&downEast
+		count0, carry3 := fullAdd(sum0, sum1, sum2)
+		twos, fours := fullAdd(carry0, carry1, carry2)
+		count1, carry4 := twos^carry3, twos&carry3
+		count2, count3 := fours^carry4, fours&carry4
+
+		alive := row[w]
+		next := uint64(0)
+		for n := 0; n <= 8; n++ {
+			if !births[n] && !survivals[n] {
+				continue
+			}
+			// Find the cells with exactly n neighbours
+			matches := countBit(count0, n&1) & countBit(count1, n&2) & countBit(count2, n&4) & countBit(count3, n&8)
+			if !births[n] {
+				matches &= alive
+			} else if !survivals[n] {
+				matches &^= alive
+			}
+			next |= matches
+		}
+		out[w] = next
+	}
+
+	// Cells past the width of the world must stay dead
+	out[last] &= ^uint64(0) >> (wordSize - 1 - lastBit)
+}
+
+// Get a word of a row moved one cell along each way, so every bit holds
+// the cell to the west or the east of the cell that bit stands for
+// Cells off an edge which isn't joined are dead
+func shiftRow(row []uint64, w, last int, lastBit uint, wrap bool) (west, east uint64) {
+	west = row[w] << 1
+	if w > 0 {
+		west |= row[w-1] >> (wordSize - 1)
+	} else if wrap {
+		west |= (row[last] >> lastBit) & 1
+	}
+
+	east = row[w] >> 1
+	if w < last {
+		east |= row[w+1] << (wordSize - 1)
+	} else if wrap {
+		east |= (row[0] & 1) << lastBit
+	}
+	return
+}
+
+// Add three words bit by bit, giving the sum and carry bits
+func fullAdd(a, b, c uint64) (sum, carry uint64) {
+	partial := a ^ b
+	return partial ^ c, a&b | partial&c
+}
+
+// Get the cells where a bit of the neighbour count matches a bit of n
+func countBit(count uint64, set int) uint64 {
+	if set != 0 {
+		return count
+	}
+	return ^count
+}
@@ -0,0 +1,90 @@
+package gol
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// Calculate the next turn of a world one cell at a time, to check the packed kernel against
+func referenceStep(world [][]uint8, imageHeight, imageWidth int, rule Rule, topology Topology) [][]uint8 {
+	next := newWorld(imageHeight, imageWidth)
+	for y := 0; y < imageHeight; y++ {
+		for x := 0; x < imageWidth; x++ {
+			neighbours := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if dx == 0 && dy == 0 {
+						continue
+					}
+					if row, col, ok := topology.Neighbour(y+dy, x+dx, imageHeight, imageWidth); ok && world[row][col] > 0 {
+						neighbours++
+					}
+				}
+			}
+			if rule.Next(world[y][x] > 0, neighbours) {
+				next[y][x] = 255
+			}
+		}
+	}
+	return next
+}
+
+// TestCalculateNextState compares the packed kernel with referenceStep on random worlds,
+// with widths around the size of a word and every topology, for whole worlds and strips of them
+func TestCalculateNextState(t *testing.T) {
+	var rules []Rule
+	for _, notation := range []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678", "B012345678/S012345678"} {
+		rule, err := ParseRule(notation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+	random := rand.New(rand.NewSource(1))
+	sizes := [][2]int{{1, 1}, {3, 5}, {8, 63}, {8, 64}, {9, 65}, {4, 127}, {5, 128}, {6, 130}}
+	for _, rule := range rules {
+		for _, topology := range []Topology{Torus, Plane, Cylinder, KleinBottle} {
+			for _, size := range sizes {
+				imageHeight, imageWidth := size[0], size[1]
+				name := rule.String() + "/" + topology.String() + "/" + strconv.Itoa(imageWidth) + "x" + strconv.Itoa(imageHeight)
+				t.Run(name, func(t *testing.T) {
+					world := newWorld(imageHeight, imageWidth)
+					for y := range world {
+						for x := range world[y] {
+							if random.Intn(3) == 0 {
+								world[y][x] = 255
+							}
+						}
+					}
+					expected := referenceStep(world, imageHeight, imageWidth, rule, topology)
+
+					// The whole world at once, and split into strips as the workers are given it
+					for _, strips := range []int{1, 3} {
+						packed := packWorld(world, imageHeight, imageWidth)
+						next := newPackedWorld(imageHeight, imageWidth)
+						for s := 0; s < strips; s++ {
+							calculateNextState(packed, next, s*imageHeight/strips, (s+1)*imageHeight/strips, imageWidth, rule, topology)
+						}
+
+						actual := next.unpack(imageHeight, imageWidth)
+						for y := range expected {
+							for x := range expected[y] {
+								if actual[y][x] != expected[y][x] {
+									t.Fatalf("%d strips: cell (%d, %d) is %d, expected %d", strips, x, y, actual[y][x], expected[y][x])
+								}
+							}
+						}
+						// Bits past the width of the world must stay dead
+						last := len(next[0]) - 1
+						for y := range next {
+							if next[y][last]&^(^uint64(0)>>uint(wordSize*len(next[y])-imageWidth)) != 0 {
+								t.Fatalf("%d strips: bits past the width of row %d are alive", strips, y)
+							}
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
 
 	"uk.ac.bris.cs/gameoflife/gol"
@@ -43,11 +44,36 @@ func main() {
 		false,
 		"Disables the SDL window, so there is no visualisation during the tests.")
 
+	rule := flag.String(
+		"rule",
+		"B3/S23",
+		"Specify the Life-like rule in B/S notation (e.g. B36/S23) or by name (e.g. highlife). Defaults to B3/S23.")
+
+	topology := flag.String(
+		"topology",
+		"torus",
+		"Specify how the board edges are joined: torus, plane, cylinder or klein. Defaults to torus.")
+
 	flag.Parse()
 
+	var err error
+	params.Rule, err = gol.ParseRule(*rule)
+	params.RuleSet = true
+	if err != nil {
+		fmt.Println("Invalid rule:", err)
+		os.Exit(1)
+	}
+	params.Topology, err = gol.ParseTopology(*topology)
+	if err != nil {
+		fmt.Println("Invalid topology:", err)
+		os.Exit(1)
+	}
+
 	fmt.Println("Threads:", params.Threads)
 	fmt.Println("Width:", params.ImageWidth)
 	fmt.Println("Height:", params.ImageHeight)
+	fmt.Println("Rule:", params.Rule)
+	fmt.Println("Topology:", params.Topology)
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)